@@ -0,0 +1,266 @@
+// Package config provides the data model for spot's task files (spt.yml): targets,
+// tasks and the command primitives (cmd, copy, script, wait, ...) that make up a task.
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/umputun/simplotask/app/secrets"
+)
+
+// activeVault decrypts !vault-tagged scalars while a PlayBook is being parsed.
+// Parsing isn't done concurrently within a single process, so a package-level
+// vault for the duration of New is simpler than threading it through every node.
+var activeVault *secrets.Vault
+
+// Secret is a string that may come from an inline `!vault |` block in spt.yml;
+// it's transparently decrypted during New and otherwise behaves like a string.
+type Secret string
+
+// UnmarshalYAML decrypts node if it carries the !vault tag or already looks
+// like a "$SPOT_VAULT;..." encoded value, otherwise it's taken as plaintext.
+func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
+	val := strings.TrimSpace(node.Value)
+	if node.Tag != "!vault" && !secrets.IsEncrypted(val) {
+		*s = Secret(val)
+		return nil
+	}
+	if activeVault == nil {
+		return fmt.Errorf("encrypted value found but no vault key configured, see --vault-key")
+	}
+	dec, err := activeVault.Decrypt(val)
+	if err != nil {
+		return fmt.Errorf("can't decrypt vault value: %w", err)
+	}
+	*s = Secret(dec)
+	return nil
+}
+
+// ConnType defines how a target's hosts should be reached.
+type ConnType string
+
+// supported connection types for a target
+const (
+	ConnTypeAuto  ConnType = ""       // not declared, connects over ssh; hosts are not probed for their type
+	ConnTypeSSH   ConnType = "ssh"
+	ConnTypeWinRM ConnType = "winrm"
+)
+
+// PlayBook represents the top-level spt.yml document: global connection defaults
+// plus the named targets and tasks it defines.
+type PlayBook struct {
+	User   string `yaml:"user"`
+	SSHKey string `yaml:"ssh_key"`
+
+	Targets map[string]Target `yaml:"targets"`
+	Tasks   map[string]Task   `yaml:"tasks"`
+	Vars    map[string]Secret `yaml:"vars"` // global vars/secrets, merged with each Task's Vars
+
+	overrides *Overrides
+}
+
+// Target describes a destination, either a static list of hosts or an inventory
+// reference, along with how those hosts should be reached.
+type Target struct {
+	Name  string   `yaml:"name"`
+	Hosts []string `yaml:"hosts"`
+	Type  ConnType `yaml:"type"` // ssh (default) or winrm, see ConnType
+
+	Proxy *ProxyConfig `yaml:"proxy"` // bastion(s) to dial through before reaching Hosts, ssh only
+	WinRM *WinRMConfig `yaml:"winrm"` // connection settings used when Type is winrm
+}
+
+// WinRMConfig configures how a winrm target is reached.
+type WinRMConfig struct {
+	HTTPS    bool   `yaml:"https"`
+	Insecure bool   `yaml:"insecure"` // skip TLS certificate verification, only used when HTTPS is set
+	CACert   string `yaml:"ca_cert"`  // CA certificate file to verify the endpoint, only used when HTTPS is set
+	Port     int    `yaml:"port"`     // overrides the default port (5985 plain, 5986 https)
+}
+
+// ProxyConfig declares a chain of SSH bastions a target's hosts are reached
+// through, equivalent to OpenSSH's ProxyJump.
+type ProxyConfig struct {
+	Jumps      []string `yaml:"jumps"`       // "user@host:port" entries, dialed in order
+	Key        string   `yaml:"key"`         // private key for the jump hosts, defaults to the key resolved for the target hosts
+	KnownHosts string   `yaml:"known_hosts"` // known_hosts file for the jump hosts
+}
+
+// Hop is one bastion in a resolved ProxyJump chain.
+type Hop struct {
+	Addr       string
+	User       string
+	Key        string
+	KnownHosts string // verifies this hop's host key, empty means no verification
+}
+
+// Hops resolves the jump chain into dialable hops, falling back to defaultUser
+// for any jump entry that doesn't carry its own user, and to defaultKey when
+// the proxy config has no Key of its own.
+func (pc *ProxyConfig) Hops(defaultUser, defaultKey string) ([]Hop, error) {
+	key := pc.Key
+	if key == "" {
+		key = defaultKey
+	}
+
+	res := make([]Hop, 0, len(pc.Jumps))
+	for _, j := range pc.Jumps {
+		user, addr := defaultUser, j
+		if at := strings.Index(j, "@"); at >= 0 {
+			user, addr = j[:at], j[at+1:]
+		}
+		if user == "" || addr == "" {
+			return nil, fmt.Errorf("invalid proxy jump %q, expected user@host:port", j)
+		}
+		res = append(res, Hop{Addr: addr, User: user, Key: key, KnownHosts: pc.KnownHosts})
+	}
+	return res, nil
+}
+
+// Task is a named, ordered list of commands run on a target.
+type Task struct {
+	Name         string            `yaml:"name"`
+	User         string            `yaml:"user"`
+	Vars         map[string]Secret `yaml:"vars"` // overrides/extends the playbook's global Vars
+	Commands     []Cmd             `yaml:"commands"`
+	ForwardAgent bool              `yaml:"ssh_forward_agent,omitempty"` // forward a local ssh-agent to commands on this task, ssh only
+}
+
+// Cmd is a single task primitive. Exactly one of Cmd/Copy/Script/Wait should be set.
+type Cmd struct {
+	Name   string `yaml:"name"`
+	Cmd    string `yaml:"cmd,omitempty"`
+	Copy   *Copy  `yaml:"copy,omitempty"`
+	Script string `yaml:"script,omitempty"`
+	Wait   *Wait  `yaml:"wait,omitempty"`
+}
+
+// Copy defines a source/destination pair for the copy primitive.
+type Copy struct {
+	Src   string `yaml:"src"`
+	Dst   string `yaml:"dst"`
+	Mkdir bool   `yaml:"mkdir,omitempty"`
+}
+
+// Wait defines a simple poll-until-ready primitive.
+type Wait struct {
+	Command       string        `yaml:"cmd"`
+	Timeout       time.Duration `yaml:"timeout"`
+	CheckDuration time.Duration `yaml:"interval"`
+}
+
+// Overrides carries CLI-provided values that take precedence over what's in the task file.
+type Overrides struct {
+	TargetHosts   []string
+	InventoryFile string
+	InventoryHTTP string
+	VaultKey      string // passphrase used to decrypt !vault-tagged values, see secrets.Vault
+}
+
+// New reads and parses a task file from fname, applying overrides on top of it.
+// Any `!vault |` blocks in the file are decrypted using overrides.VaultKey.
+func New(fname string, overrides *Overrides) (*PlayBook, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("can't read config %s: %w", fname, err)
+	}
+
+	if overrides != nil && overrides.VaultKey != "" {
+		v, err := secrets.New(overrides.VaultKey)
+		if err != nil {
+			return nil, fmt.Errorf("can't init vault: %w", err)
+		}
+		activeVault = v
+		defer func() { activeVault = nil }()
+	}
+
+	res := &PlayBook{overrides: overrides}
+	if err := yaml.Unmarshal(data, res); err != nil {
+		return nil, fmt.Errorf("can't parse config %s: %w", fname, err)
+	}
+
+	if overrides != nil && len(overrides.TargetHosts) > 0 {
+		if res.Targets == nil {
+			res.Targets = map[string]Target{}
+		}
+		tgt := res.Targets["default"] // preserve any Type/Proxy already declared for it
+		tgt.Name = "default"
+		tgt.Hosts = overrides.TargetHosts
+		res.Targets["default"] = tgt
+	}
+
+	if err := res.loadInventory(); err != nil {
+		return nil, fmt.Errorf("can't load inventory: %w", err)
+	}
+
+	return res, nil
+}
+
+// loadInventory merges hosts from an inventory file or URL, if configured, into the default target.
+func (p *PlayBook) loadInventory() error {
+	if p.overrides == nil {
+		return nil
+	}
+
+	var lines []string
+	switch {
+	case p.overrides.InventoryFile != "":
+		data, err := os.ReadFile(p.overrides.InventoryFile)
+		if err != nil {
+			return fmt.Errorf("can't read inventory file: %w", err)
+		}
+		lines = strings.Split(string(data), "\n")
+	case p.overrides.InventoryHTTP != "":
+		resp, err := http.Get(p.overrides.InventoryHTTP) //nolint:gosec,noctx // url comes from trusted local config
+		if err != nil {
+			return fmt.Errorf("can't fetch inventory: %w", err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("can't read inventory response: %w", err)
+		}
+		lines = strings.Split(string(data), "\n")
+	default:
+		return nil
+	}
+
+	tgt := p.Targets["default"]
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			tgt.Hosts = append(tgt.Hosts, l)
+		}
+	}
+	tgt.Name = "default"
+	p.Targets["default"] = tgt
+	return nil
+}
+
+// TargetHosts returns the resolved host list for the given target name.
+func (p *PlayBook) TargetHosts(name string) ([]string, error) {
+	tgt, ok := p.Targets[name]
+	if !ok {
+		return nil, fmt.Errorf("target %q not found", name)
+	}
+	return tgt.Hosts, nil
+}
+
+// MergedVars returns the global Vars merged with taskName's own Vars, task
+// values winning on conflict, decrypted and ready to inject as {{ .Vars }} / env.
+func (p *PlayBook) MergedVars(taskName string) map[string]string {
+	res := make(map[string]string, len(p.Vars))
+	for k, v := range p.Vars {
+		res[k] = string(v)
+	}
+	for k, v := range p.Tasks[taskName].Vars {
+		res[k] = string(v)
+	}
+	return res
+}
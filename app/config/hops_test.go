@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestProxyConfigHops(t *testing.T) {
+	pc := &ProxyConfig{Jumps: []string{"alice@bastion1:22", "bastion2:2222"}}
+
+	hops, err := pc.Hops("bob", "default-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hops) != 2 {
+		t.Fatalf("expected 2 hops, got %d", len(hops))
+	}
+
+	if hops[0] != (Hop{Addr: "bastion1:22", User: "alice", Key: "default-key"}) {
+		t.Errorf("unexpected first hop: %+v", hops[0])
+	}
+	// a jump without an explicit user falls back to defaultUser
+	if hops[1] != (Hop{Addr: "bastion2:2222", User: "bob", Key: "default-key"}) {
+		t.Errorf("unexpected second hop: %+v", hops[1])
+	}
+}
+
+func TestProxyConfigHopsOwnKey(t *testing.T) {
+	pc := &ProxyConfig{Jumps: []string{"alice@bastion1:22"}, Key: "bastion-key"}
+
+	hops, err := pc.Hops("bob", "default-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hops[0].Key != "bastion-key" {
+		t.Errorf("expected the proxy's own key to win over defaultKey, got %q", hops[0].Key)
+	}
+}
+
+func TestProxyConfigHopsEmpty(t *testing.T) {
+	pc := &ProxyConfig{}
+	hops, err := pc.Hops("bob", "default-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hops) != 0 {
+		t.Errorf("expected no hops, got %+v", hops)
+	}
+}
+
+func TestProxyConfigHopsInvalid(t *testing.T) {
+	pc := &ProxyConfig{Jumps: []string{"alice@"}}
+	if _, err := pc.Hops("bob", "default-key"); err == nil {
+		t.Error("expected an error for a jump without a host")
+	}
+}
+
+func TestProxyConfigHopsMissingUser(t *testing.T) {
+	pc := &ProxyConfig{Jumps: []string{"@bastion1:22"}}
+	if _, err := pc.Hops("", "default-key"); err == nil {
+		t.Error("expected an error when neither the jump nor defaultUser supply a user")
+	}
+}
@@ -0,0 +1,195 @@
+// Package logging provides a size/age rotating file writer for spot's log
+// output, lumberjack-style, so long unattended runs over many hosts don't grow
+// a single unbounded log file.
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RotatingWriter writes to Filename, rotating it out once it exceeds MaxSizeMB,
+// keeping at most MaxBackups old copies no older than MaxAgeDays, optionally
+// gzip-compressing them. It's safe for concurrent use by multiple goroutines.
+type RotatingWriter struct {
+	Filename   string
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+	Compress   bool
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// New creates a RotatingWriter and opens (or creates) Filename for appending.
+func New(filename string, maxSizeMB, maxBackups, maxAgeDays int, compress bool) (*RotatingWriter, error) {
+	w := &RotatingWriter{
+		Filename: filename, MaxSizeMB: maxSizeMB, MaxBackups: maxBackups, MaxAgeDays: maxAgeDays, Compress: compress,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.Filename), 0o755); err != nil {
+		return fmt.Errorf("can't create log dir: %w", err)
+	}
+	f, err := os.OpenFile(w.Filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("can't open log file %s: %w", w.Filename, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close() //nolint:errcheck // best effort cleanup
+		return fmt.Errorf("can't stat log file %s: %w", w.Filename, err)
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write implements io.Writer, rotating the underlying file first if p would push it past MaxSizeMB.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	maxBytes := int64(w.MaxSizeMB) * 1024 * 1024
+	if maxBytes > 0 && w.size+int64(len(p)) > maxBytes {
+		if err := w.rotate(); err != nil {
+			return 0, fmt.Errorf("can't rotate log file: %w", err)
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate closes the current file, renames it aside with a timestamp suffix
+// (optionally gzipping it), reopens Filename fresh, and prunes old backups.
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("can't close log file: %w", err)
+	}
+
+	backup := fmt.Sprintf("%s.%s", w.Filename, time.Now().Format("20060102T150405.000"))
+	if err := os.Rename(w.Filename, backup); err != nil {
+		return fmt.Errorf("can't rename log file: %w", err)
+	}
+
+	if w.Compress {
+		if err := gzipAndRemove(backup); err != nil {
+			return fmt.Errorf("can't compress rotated log file: %w", err)
+		}
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+	return w.prune()
+}
+
+func gzipAndRemove(path string) error {
+	src, err := os.Open(path) //nolint:gosec // path is our own rotated log filename
+	if err != nil {
+		return err
+	}
+	defer src.Close() //nolint:errcheck // best effort cleanup
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close() //nolint:errcheck,gosec // best effort cleanup on error path
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// prune removes backups beyond MaxBackups or older than MaxAgeDays.
+func (w *RotatingWriter) prune() error {
+	if w.MaxBackups <= 0 && w.MaxAgeDays <= 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(w.Filename)
+	base := filepath.Base(w.Filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("can't list log dir: %w", err)
+	}
+
+	backupRe := regexp.MustCompile(regexp.QuoteMeta(base) + `\.\d{8}T\d{6}\.\d{3}(\.gz)?$`)
+	var backups []string
+	for _, e := range entries {
+		if !e.IsDir() && backupRe.MatchString(e.Name()) {
+			backups = append(backups, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(backups) // timestamp suffix sorts lexically == chronologically
+
+	if w.MaxAgeDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -w.MaxAgeDays)
+		kept := backups[:0]
+		for _, b := range backups {
+			info, err := os.Stat(b)
+			if err == nil && info.ModTime().Before(cutoff) {
+				os.Remove(b) //nolint:errcheck // best effort pruning
+				continue
+			}
+			kept = append(kept, b)
+		}
+		backups = kept
+	}
+
+	if w.MaxBackups > 0 && len(backups) > w.MaxBackups {
+		for _, b := range backups[:len(backups)-w.MaxBackups] {
+			os.Remove(b) //nolint:errcheck // best effort pruning
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying file.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// StripANSI wraps w so that color escape codes in what's written to it are
+// removed first, for sinks (like a log file) that shouldn't carry terminal colors.
+func StripANSI(w io.Writer) io.Writer {
+	return &stripANSIWriter{w: w}
+}
+
+type stripANSIWriter struct{ w io.Writer }
+
+func (s *stripANSIWriter) Write(p []byte) (int, error) {
+	clean := ansiRe.ReplaceAll(p, nil)
+	if _, err := s.w.Write(clean); err != nil {
+		return 0, err
+	}
+	return len(p), nil // report the original length so multi-writer callers don't see a short write
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingWriterRotatesOnSize(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "spt.log")
+
+	w, err := New(logFile, 1, 0, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	const mb = 1024 * 1024
+	if _, err := w.Write(make([]byte, mb-1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("xx")); err != nil { // pushes size past MaxSizeMB, triggers rotation
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backups int
+	for _, e := range entries {
+		if e.Name() != "spt.log" {
+			backups++
+		}
+	}
+	if backups != 1 {
+		t.Fatalf("expected exactly 1 rotated backup, got %d (entries: %v)", backups, entries)
+	}
+}
+
+func TestRotatingWriterPrunesMaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "spt.log")
+
+	w, err := New(logFile, 1, 2, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	const mb = 1024 * 1024
+	for i := 0; i < 4; i++ {
+		if _, err := w.Write(make([]byte, mb)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backups int
+	for _, e := range entries {
+		if e.Name() != "spt.log" {
+			backups++
+		}
+	}
+	if backups > 2 {
+		t.Errorf("expected at most 2 backups kept (MaxBackups), got %d", backups)
+	}
+}
+
+func TestRotatingWriterCompress(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "spt.log")
+
+	w, err := New(logFile, 1, 0, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	const mb = 1024 * 1024
+	if _, err := w.Write(make([]byte, mb+1)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gzFound bool
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".gz") {
+			gzFound = true
+		}
+	}
+	if !gzFound {
+		t.Error("expected a .gz rotated backup, found none")
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	var buf strings.Builder
+	w := StripANSI(&buf)
+
+	n, err := w.Write([]byte("\x1b[31merror\x1b[0m: boom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len("\x1b[31merror\x1b[0m: boom") {
+		t.Errorf("expected reported length to match the original write, got %d", n)
+	}
+	if buf.String() != "error: boom" {
+		t.Errorf("expected ANSI codes stripped, got %q", buf.String())
+	}
+}
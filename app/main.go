@@ -14,8 +14,11 @@ import (
 	"github.com/jessevdk/go-flags"
 
 	"github.com/umputun/simplotask/app/config"
+	"github.com/umputun/simplotask/app/logging"
 	"github.com/umputun/simplotask/app/remote"
 	"github.com/umputun/simplotask/app/runner"
+	"github.com/umputun/simplotask/app/secrets"
+	"github.com/umputun/simplotask/app/server"
 )
 
 type options struct {
@@ -30,14 +33,45 @@ type options struct {
 	InventoryHTTP string   `short:"H" long:"inventory-http" description:"inventory http url"`
 
 	// connection overrides
-	SSHUser string `short:"u" long:"user" description:"ssh user"`
-	SSHKey  string `short:"k" long:"key" description:"ssh key" default:"~/.ssh/id_rsa"`
+	SSHUser  string `short:"u" long:"user" description:"ssh user"`
+	SSHKey   string `short:"k" long:"key" description:"ssh key" default:"~/.ssh/id_rsa"`
+	ConnType string `long:"conn-type" description:"connection type, ssh or winrm, overrides target's type" choice:"ssh" choice:"winrm"`
+	SSHProxy string `long:"ssh-proxy" description:"bastion host to ProxyJump through, user@host:port, overrides target's proxy"`
+	SSHAgent bool   `long:"ssh-agent" description:"fall back to ssh-agent ($SSH_AUTH_SOCK) keys if the explicit ssh key can't be loaded"`
+
+	// winrm overrides, only used when the resolved connection type is winrm
+	WinRMHTTPS    bool   `long:"winrm-https" description:"use https for winrm connections, overrides target's winrm.https"`
+	WinRMInsecure bool   `long:"winrm-insecure" description:"skip TLS certificate verification for winrm https connections"`
+	WinRMCACert   string `long:"winrm-ca-cert" description:"CA certificate file to verify the winrm https endpoint"`
+	WinRMPort     int    `long:"winrm-port" description:"override the default winrm port (5985 plain, 5986 https)"`
+
+	// vault, for decrypting !vault-tagged values in the task file
+	VaultKey     string `long:"vault-key" env:"SPOT_VAULT_KEY" description:"vault key to decrypt secrets in the task file"`
+	VaultKeyFile string `long:"vault-key-file" description:"file with the vault key, alternative to --vault-key"`
 
 	Skip []string `short:"s" long:"skip" description:"skip commands"`
 	Only []string `short:"o" long:"only" description:"run only commands"`
 
 	Dbg bool `long:"dbg" description:"debug mode"`
 	Dev bool `long:"dev" description:"development mode"`
+
+	// rotating file log, for long unattended runs
+	LogFile       string `long:"log-file" description:"write logs to this file, rotating it, in addition to stdout/stderr"`
+	LogMaxSize    int    `long:"log-max-size" description:"max size of the log file in MB before it's rotated" default:"100"`
+	LogMaxBackups int    `long:"log-max-backups" description:"max number of rotated log files to keep" default:"3"`
+	LogMaxAge     int    `long:"log-max-age" description:"max age in days of a rotated log file to keep" default:"28"`
+	LogCompress   bool   `long:"log-compress" description:"gzip rotated log files"`
+
+	// server mode, runs an HTTP API instead of the one-shot CLI flow
+	Serve        bool   `long:"serve" description:"run in server mode, exposing an HTTP API instead of running once"`
+	Listen       string `long:"listen" description:"address to listen on in server mode" default:":8080"`
+	ServerTLSCrt string `long:"server-tls-cert" description:"TLS certificate file for server mode"`
+	ServerTLSKey string `long:"server-tls-key" description:"TLS key file for server mode"`
+	AuthToken    string `long:"auth-token" env:"SPOT_AUTH_TOKEN" description:"bearer token required by server mode, disabled if empty"`
+	JobPoolSize  int    `long:"job-pool-size" description:"max number of jobs running concurrently in server mode" default:"4"`
+	JobHistory   int    `long:"job-history" description:"max number of completed jobs kept in memory in server mode" default:"100"`
+
+	Vault vaultCommand `command:"vault" description:"encrypt, decrypt or edit vault-protected secrets"`
 }
 
 var revision = "latest"
@@ -53,7 +87,11 @@ func main() {
 		}
 		os.Exit(1)
 	}
-	setupLog(opts.Dbg, opts.Dev)
+	setupLog(opts)
+
+	if p.Active != nil && p.Active.Name == "vault" {
+		return // vault subcommand already ran via its Execute method
+	}
 
 	if err := run(opts); err != nil {
 		log.Panicf("[ERROR] %v", err)
@@ -70,26 +108,106 @@ func run(opts options) error {
 		cancel()
 	}()
 
-	conf, err := config.New(opts.TaskFile,
-		&config.Overrides{TargetHosts: opts.TargetHosts, InventoryFile: opts.InventoryFile, InventoryHTTP: opts.InventoryHTTP})
+	vaultKey := opts.VaultKey
+	if vaultKey == "" && opts.VaultKeyFile != "" {
+		key, err := secrets.ReadKeyFile(opts.VaultKeyFile)
+		if err != nil {
+			return fmt.Errorf("can't read vault key file: %w", err)
+		}
+		vaultKey = key
+	}
+
+	conf, err := config.New(opts.TaskFile, &config.Overrides{
+		TargetHosts: opts.TargetHosts, InventoryFile: opts.InventoryFile, InventoryHTTP: opts.InventoryHTTP,
+		VaultKey: vaultKey,
+	})
 	if err != nil {
 		return fmt.Errorf("can't read config: %w", err)
 	}
 
-	connector, err := remote.NewConnector(sshUserAndKey(opts, conf))
-	if err != nil {
-		return fmt.Errorf("can't create connector: %w", err)
+	user, key := sshUserAndKey(opts, conf)
+	connectorFor := func(target config.Target) (remote.Connector, error) {
+		connType := string(target.Type)
+		if opts.ConnType != "" { // cli flag wins over the target's declared type
+			connType = opts.ConnType
+		}
+
+		proxy, err := proxyHops(opts, user, key, target)
+		if err != nil {
+			return nil, err
+		}
+		return remote.NewConnector(connType, user, key, proxy, opts.SSHAgent, winrmSettings(opts, target))
 	}
+
+	if opts.Serve {
+		srv := server.New(conf, connectorFor, opts.JobPoolSize)
+		srv.Addr, srv.TLSCert, srv.TLSKey = opts.Listen, opts.ServerTLSCrt, opts.ServerTLSKey
+		srv.Token, srv.HistoryCap, srv.Concurrency = opts.AuthToken, opts.JobHistory, opts.Concurrent
+		log.Printf("[INFO] starting server on %s", opts.Listen)
+		return srv.ListenAndServe(ctx)
+	}
+
 	r := runner.Process{
-		Concurrency: opts.Concurrent,
-		Connector:   connector,
-		Config:      conf,
-		Only:        opts.Only,
-		Skip:        opts.Skip,
+		Concurrency:  opts.Concurrent,
+		ConnectorFor: connectorFor,
+		Config:       conf,
+		Only:         opts.Only,
+		Skip:         opts.Skip,
 	}
 	return r.Run(ctx, opts.TaskName, opts.TargetName)
 }
 
+// proxyHops resolves the bastion chain to dial through for target: the
+// --ssh-proxy flag (a single hop) takes precedence over the target's own
+// proxy.jumps chain from spt.yml.
+func proxyHops(opts options, user, key string, target config.Target) ([]remote.ProxyHop, error) {
+	if opts.SSHProxy != "" {
+		hops, err := (&config.ProxyConfig{Jumps: []string{opts.SSHProxy}}).Hops(user, key)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse --ssh-proxy: %w", err)
+		}
+		return toRemoteHops(hops), nil
+	}
+	if target.Proxy == nil {
+		return nil, nil
+	}
+	hops, err := target.Proxy.Hops(user, key)
+	if err != nil {
+		return nil, fmt.Errorf("can't resolve proxy for target %s: %w", target.Name, err)
+	}
+	return toRemoteHops(hops), nil
+}
+
+// winrmSettings resolves the winrm connection knobs for target: cli flags win
+// over whatever's declared under the target's own winrm: section.
+func winrmSettings(opts options, target config.Target) remote.WinRMSettings {
+	var s remote.WinRMSettings
+	if target.WinRM != nil {
+		s.HTTPS, s.Insecure, s.CACertPath, s.Port = target.WinRM.HTTPS, target.WinRM.Insecure, target.WinRM.CACert, target.WinRM.Port
+	}
+	if opts.WinRMHTTPS {
+		s.HTTPS = true
+	}
+	if opts.WinRMInsecure {
+		s.Insecure = true
+	}
+	if opts.WinRMCACert != "" {
+		s.CACertPath = opts.WinRMCACert
+	}
+	if opts.WinRMPort != 0 {
+		s.Port = opts.WinRMPort
+	}
+	return s
+}
+
+func toRemoteHops(hops []config.Hop) []remote.ProxyHop {
+	res := make([]remote.ProxyHop, len(hops))
+	for i, h := range hops {
+		res[i] = remote.ProxyHop{Addr: h.Addr, User: h.User, Key: h.Key, KnownHosts: h.KnownHosts}
+	}
+	return res
+}
+
 func sshUserAndKey(opts options, conf *config.PlayBook) (user, key string) {
 	sshUser := conf.User // default to global config user
 	if tsk, ok := conf.Tasks[opts.TaskName]; ok && tsk.User != "" {
@@ -106,17 +224,32 @@ func sshUserAndKey(opts options, conf *config.PlayBook) (user, key string) {
 	return sshUser, sshKey
 }
 
-func setupLog(dbg, dev bool) {
-	logOpts := []lgr.Option{lgr.Out(io.Discard), lgr.Err(io.Discard)} // default to discard
-	if dbg {
+func setupLog(opts options) {
+	out, errOut := io.Writer(io.Discard), io.Writer(io.Discard) // default to discard
+	logOpts := []lgr.Option{}
+	if opts.Dbg {
 		// debug mode shows all messages but no caller/stack trace
 		logOpts = []lgr.Option{lgr.Debug, lgr.Msec, lgr.LevelBraces, lgr.StackTraceOnError}
+		out, errOut = os.Stdout, os.Stderr
 	}
-	if dev {
+	if opts.Dev {
 		// dev mode shows all messages with caller/stack trace
 		logOpts = []lgr.Option{lgr.Debug, lgr.CallerFile, lgr.CallerFunc, lgr.Msec, lgr.LevelBraces, lgr.StackTraceOnError}
+		out, errOut = os.Stdout, os.Stderr
 	}
 
+	if opts.LogFile != "" {
+		rw, err := logging.New(opts.LogFile, opts.LogMaxSize, opts.LogMaxBackups, opts.LogMaxAge, opts.LogCompress)
+		if err != nil {
+			log.Printf("[WARN] can't set up log file %s: %v", opts.LogFile, err)
+		} else {
+			// the colorizer below still runs for every sink, so the file gets ANSI codes stripped here
+			fileSink := logging.StripANSI(rw)
+			out, errOut = io.MultiWriter(out, fileSink), io.MultiWriter(errOut, fileSink)
+		}
+	}
+	logOpts = append(logOpts, lgr.Out(out), lgr.Err(errOut))
+
 	colorizer := lgr.Mapper{
 		ErrorFunc:  func(s string) string { return color.New(color.FgHiRed).Sprint(s) },
 		WarnFunc:   func(s string) string { return color.New(color.FgRed).Sprint(s) },
@@ -129,4 +262,4 @@ func setupLog(dbg, dev bool) {
 
 	lgr.SetupStdLogger(logOpts...)
 	lgr.Setup(logOpts...)
-}
\ No newline at end of file
+}
@@ -0,0 +1,155 @@
+// Package remote provides connectors able to run commands, copy files and manage
+// a remote session, abstracting over the underlying transport (ssh, winrm, ...).
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Connector abstracts a connection to a single remote host, regardless of the
+// underlying transport. All task primitives (cmd, copy, script, wait) are
+// implemented on top of these methods.
+type Connector interface {
+	// Connect opens a session to hostAddr, ready to run commands against it.
+	// forwardAgent requests ssh-agent forwarding to the remote side; transports
+	// that can't support it (e.g. winrm) ignore it.
+	Connect(ctx context.Context, hostAddr, hostName, user string, forwardAgent bool) (Session, error)
+}
+
+// Session represents an established connection to a single remote host.
+type Session interface {
+	// Run executes cmd on the remote host, writing combined stdout/stderr to out.
+	Run(ctx context.Context, cmd string, out io.Writer) error
+	// Upload copies local src to remote dst, creating parent directories if mkdir is set.
+	Upload(ctx context.Context, local, remote string, mkdir bool) error
+	// Close releases any resources associated with the session.
+	Close() error
+	// Shell reports the command-line dialect Run expects, so callers building
+	// multi-statement command lines (e.g. injecting vars as env) speak the right shell.
+	Shell() ShellKind
+}
+
+// ShellKind identifies a remote command-line dialect.
+type ShellKind string
+
+// shell dialects understood by the sessions this package implements.
+const (
+	ShellPOSIX      ShellKind = "posix"      // sh/bash-compatible, used by SSHConnector sessions
+	ShellPowerShell ShellKind = "powershell" // used by WinRMConnector sessions
+)
+
+// ProxyHop is one bastion in a ProxyJump chain, dialed in order before the
+// final target host. An empty chain means connect directly.
+type ProxyHop struct {
+	Addr       string
+	User       string
+	Key        string
+	KnownHosts string // verifies this hop's host key, empty means no verification
+}
+
+// WinRMSettings carries the winrm-specific connection knobs that WithWinRMTLS/
+// WithWinRMPort expose, resolved by the caller (cli flag > target's own config).
+type WinRMSettings struct {
+	HTTPS      bool
+	Insecure   bool
+	CACertPath string
+	Port       int
+}
+
+// NewConnector creates a Connector for the given connection type. connType is
+// one of "" (defaults to ssh) or "winrm"; user/key/password follow the same
+// override precedence already used for ssh (cli flag > task > global config).
+// proxy and useAgent are only honored for ssh; winrm is only honored for winrm;
+// both are ignored for other transports. useAgent makes the ssh connector fall
+// back to keys served by ssh-agent (via $SSH_AUTH_SOCK) whenever the explicit
+// key can't be loaded.
+func NewConnector(connType, user, key string, proxy []ProxyHop, useAgent bool, winrm WinRMSettings) (Connector, error) {
+	switch connType {
+	case "", "ssh":
+		return NewSSHConnector(user, key, proxy, useAgent)
+	case "winrm":
+		var opts []WinRMOption
+		if winrm.HTTPS {
+			opts = append(opts, WithWinRMTLS(winrm.Insecure, winrm.CACertPath))
+		}
+		if winrm.Port != 0 {
+			opts = append(opts, WithWinRMPort(winrm.Port))
+		}
+		return NewWinRMConnector(user, key, opts...)
+	default:
+		return nil, fmt.Errorf("unknown connector type %q", connType)
+	}
+}
+
+// sshConfig builds the client config shared by the ssh connector and anything
+// dialing through it (e.g. a bastion), given a user and private key path. If
+// the key can't be loaded, it falls back to ssh-agent (if useAgent is set or
+// $SSH_AUTH_SOCK is present); if both fail, the original key error wins.
+// knownHostsPath, when set, verifies the remote host key against that file;
+// empty leaves the connection unverified.
+func sshConfig(user, keyPath string, useAgent bool, knownHostsPath string) (*ssh.ClientConfig, error) {
+	hostKeyCallback, err := hostKeyCallback(knownHostsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, keyErr := loadSigner(keyPath)
+	if keyErr == nil {
+		return &ssh.ClientConfig{
+			User:            user,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKeyCallback,
+		}, nil
+	}
+
+	if useAgent || os.Getenv("SSH_AUTH_SOCK") != "" {
+		auth, err := agentAuthMethod()
+		if err == nil {
+			return &ssh.ClientConfig{
+				User:            user,
+				Auth:            []ssh.AuthMethod{auth},
+				HostKeyCallback: hostKeyCallback,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("can't load ssh key %s: %w", keyPath, keyErr)
+}
+
+// hostKeyCallback builds a callback that verifies the remote host key against
+// knownHostsPath. An empty path means no verification is configured, which
+// leaves connections open to MITM - matches the absence of a known_hosts
+// setting on the final target host today.
+func hostKeyCallback(knownHostsPath string) (ssh.HostKeyCallback, error) {
+	if knownHostsPath == "" {
+		return ssh.InsecureIgnoreHostKey(), nil //nolint:gosec // no known_hosts configured for this hop
+	}
+	cb, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("can't load known_hosts %s: %w", knownHostsPath, err)
+	}
+	return cb, nil
+}
+
+// agentAuthMethod dials $SSH_AUTH_SOCK and returns an auth method backed by
+// whatever keys the running ssh-agent is holding.
+func agentAuthMethod() (ssh.AuthMethod, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, fmt.Errorf("SSH_AUTH_SOCK not set")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to ssh-agent at %s: %w", sock, err)
+	}
+	ag := agent.NewClient(conn)
+	return ssh.PublicKeysCallback(ag.Signers), nil
+}
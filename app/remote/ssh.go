@@ -0,0 +1,274 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// SSHConnector connects to hosts over ssh, optionally dialing through a chain
+// of bastion hosts (ProxyJump) before reaching the final target.
+type SSHConnector struct {
+	user     string
+	key      string
+	proxy    []ProxyHop
+	useAgent bool // fall back to ssh-agent keys when the explicit key can't be loaded
+
+	mu   sync.Mutex
+	jump map[string]*ssh.Client // bastion connections, keyed by hop address, reused across Connect calls
+}
+
+// NewSSHConnector creates an ssh-backed Connector for the given user/key,
+// dialing through proxy (if non-empty) before reaching any target host.
+func NewSSHConnector(user, key string, proxy []ProxyHop, useAgent bool) (*SSHConnector, error) {
+	return &SSHConnector{user: user, key: key, proxy: proxy, useAgent: useAgent}, nil
+}
+
+// Connect opens an ssh session to hostAddr, through the bastion chain if
+// configured. With forwardAgent set, a connected local ssh-agent is forwarded
+// to the remote session so commands there (e.g. `git clone` of a private repo)
+// can use it too.
+func (s *SSHConnector) Connect(ctx context.Context, hostAddr, hostName, user string, forwardAgent bool) (Session, error) {
+	if user == "" {
+		user = s.user
+	}
+	cfg, err := sshConfig(user, s.key, s.useAgent, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.proxy) == 0 {
+		client, err := dialSSHContext(ctx, hostAddr, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("can't connect to %s (%s): %w", hostName, hostAddr, err)
+		}
+		return &sshSession{client: client, forwardAgent: forwardAgent}, nil
+	}
+
+	bastion, err := s.dialChain(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't dial proxy chain for %s: %w", hostName, err)
+	}
+	conn, err := bastion.Dial("tcp", hostAddr)
+	if err != nil {
+		return nil, fmt.Errorf("can't reach %s (%s) through proxy: %w", hostName, hostAddr, err)
+	}
+	ncc, chans, reqs, err := ssh.NewClientConn(conn, hostAddr, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("can't handshake with %s (%s): %w", hostName, hostAddr, err)
+	}
+	return &sshSession{client: ssh.NewClient(ncc, chans, reqs), forwardAgent: forwardAgent}, nil
+}
+
+// dialChain dials through each configured bastion hop in order, reusing
+// already-established hops so repeated calls (one per task host, or across
+// tasks against the same target) don't reconnect the whole chain every time.
+func (s *SSHConnector) dialChain(ctx context.Context) (*ssh.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jump == nil {
+		s.jump = map[string]*ssh.Client{}
+	}
+
+	var current *ssh.Client
+	for _, hop := range s.proxy {
+		if cached, ok := s.jump[hop.Addr]; ok {
+			current = cached
+			continue
+		}
+
+		hopCfg, err := sshConfig(hop.User, hop.Key, s.useAgent, hop.KnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("can't build config for jump %s: %w", hop.Addr, err)
+		}
+
+		var client *ssh.Client
+		if current == nil {
+			client, err = dialSSHContext(ctx, hop.Addr, hopCfg)
+		} else {
+			conn, dialErr := current.Dial("tcp", hop.Addr)
+			if dialErr != nil {
+				return nil, fmt.Errorf("can't reach jump %s: %w", hop.Addr, dialErr)
+			}
+			var ncc ssh.Conn
+			var chans <-chan ssh.NewChannel
+			var reqs <-chan *ssh.Request
+			ncc, chans, reqs, err = ssh.NewClientConn(conn, hop.Addr, hopCfg)
+			if err == nil {
+				client = ssh.NewClient(ncc, chans, reqs)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't connect to jump %s: %w", hop.Addr, err)
+		}
+		s.jump[hop.Addr] = client
+		current = client
+	}
+	return current, nil
+}
+
+// dialSSHContext dials hostAddr honoring ctx cancellation.
+func dialSSHContext(ctx context.Context, hostAddr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+	type result struct {
+		client *ssh.Client
+		err    error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		client, err := ssh.Dial("tcp", hostAddr, cfg)
+		ch <- result{client, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		return r.client, r.err
+	}
+}
+
+type sshSession struct {
+	client       *ssh.Client
+	forwardAgent bool
+
+	agentOnce sync.Once
+	agentErr  error
+}
+
+// ensureAgentForwarding registers the auth-agent@openssh.com channel handler
+// on s.client, once. agent.ForwardToAgent errors if called twice for the same
+// *ssh.Client, but every command in a task reuses the same session/client, so
+// this must run once per session rather than once per Run.
+func (s *sshSession) ensureAgentForwarding() error {
+	s.agentOnce.Do(func() {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			s.agentErr = fmt.Errorf("SSH_AUTH_SOCK not set, nothing to forward")
+			return
+		}
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			s.agentErr = fmt.Errorf("can't connect to ssh-agent at %s: %w", sock, err)
+			return
+		}
+		ag := agent.NewClient(conn)
+		s.agentErr = agent.ForwardToAgent(s.client, ag)
+	})
+	return s.agentErr
+}
+
+func (s *sshSession) Run(ctx context.Context, cmd string, out io.Writer) error {
+	sess, err := s.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("can't open ssh session: %w", err)
+	}
+	defer sess.Close() //nolint:errcheck // best effort cleanup
+
+	if s.forwardAgent {
+		if err := s.ensureAgentForwarding(); err != nil {
+			return fmt.Errorf("can't forward ssh agent: %w", err)
+		}
+		if err := agent.RequestAgentForwarding(sess); err != nil {
+			return fmt.Errorf("can't request ssh agent forwarding: %w", err)
+		}
+	}
+
+	// x/crypto/ssh copies stdout and stderr on two separate goroutines; pointing
+	// both at the same out means those goroutines write it concurrently, so it
+	// needs its own lock even though out itself (e.g. a hostWriter) isn't one.
+	synced := &syncWriter{w: out}
+	sess.Stdout = synced
+	sess.Stderr = synced
+
+	done := make(chan error, 1)
+	go func() { done <- sess.Run(cmd) }()
+
+	select {
+	case <-ctx.Done():
+		_ = sess.Signal(ssh.SIGKILL)
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
+
+func (s *sshSession) Upload(ctx context.Context, local, remote string, mkdir bool) error {
+	sess, err := s.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("can't open ssh session: %w", err)
+	}
+	defer sess.Close() //nolint:errcheck // best effort cleanup
+
+	if mkdir {
+		if err := s.Run(ctx, fmt.Sprintf("mkdir -p %s", filepath.Dir(remote)), io.Discard); err != nil {
+			return fmt.Errorf("can't create remote dir: %w", err)
+		}
+	}
+
+	data, err := os.ReadFile(local)
+	if err != nil {
+		return fmt.Errorf("can't read local file %s: %w", local, err)
+	}
+
+	w, err := sess.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("can't open stdin pipe: %w", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- sess.Run(fmt.Sprintf("cat > %s", remote)) }()
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("can't write to remote: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("can't close stdin pipe: %w", err)
+	}
+	return <-errCh
+}
+
+func (s *sshSession) Close() error {
+	return s.client.Close()
+}
+
+// Shell reports that commands run through this session are interpreted by a POSIX shell.
+func (s *sshSession) Shell() ShellKind {
+	return ShellPOSIX
+}
+
+// syncWriter serializes concurrent writes to w, needed because ssh.Session
+// copies stdout and stderr to the same io.Writer on two separate goroutines.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// loadSigner reads and parses a private key from path, expanding a leading ~.
+func loadSigner(path string) (ssh.Signer, error) {
+	if len(path) > 0 && path[0] == '~' {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("can't resolve home dir: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(data)
+}
@@ -0,0 +1,323 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHServer is a minimal in-process sshd: it accepts any public key, and for
+// every "session" channel's "exec" request it echoes the command back as output
+// and exits 0. Enough surface to exercise SSHConnector/sshSession end to end.
+type testSSHServer struct {
+	addr     string
+	hostKey  ssh.Signer
+	listener net.Listener
+}
+
+func startTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &testSSHServer{addr: ln.Addr().String(), hostKey: hostKey, listener: ln}
+	cfg := &ssh.ServerConfig{PublicKeyCallback: func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) {
+		return nil, nil // accept any key, auth isn't what's under test here
+	}}
+	cfg.AddHostKey(hostKey)
+
+	go srv.serve(t, cfg)
+	t.Cleanup(func() { ln.Close() }) //nolint:errcheck // best effort cleanup
+	return srv
+}
+
+func (srv *testSSHServer) serve(t *testing.T, cfg *ssh.ServerConfig) {
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			return // listener closed, test is done
+		}
+		go srv.handleConn(t, conn, cfg)
+	}
+}
+
+func (srv *testSSHServer) handleConn(t *testing.T, conn net.Conn, cfg *ssh.ServerConfig) {
+	sc, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	defer sc.Close() //nolint:errcheck // best effort cleanup
+	go ssh.DiscardRequests(reqs)
+
+	for ch := range chans {
+		switch ch.ChannelType() {
+		case "session":
+			channel, requests, err := ch.Accept()
+			if err != nil {
+				continue
+			}
+			go srv.handleSession(t, channel, requests)
+		case "direct-tcpip":
+			go srv.handleDirectTCPIP(t, ch)
+		default:
+			ch.Reject(ssh.UnknownChannelType, "unsupported channel type") //nolint:errcheck
+		}
+	}
+}
+
+// handleDirectTCPIP services a bastion-style port forward: it dials the
+// requested destination itself and splices the channel to that connection,
+// so a client proxy-jumping through this fake sshd reaches a real TCP peer.
+func (srv *testSSHServer) handleDirectTCPIP(t *testing.T, newChan ssh.NewChannel) {
+	var dest struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChan.ExtraData(), &dest); err != nil {
+		newChan.Reject(ssh.ConnectionFailed, "bad forward request") //nolint:errcheck
+		return
+	}
+
+	target, err := net.Dial("tcp", net.JoinHostPort(dest.Host, strconv.Itoa(int(dest.Port))))
+	if err != nil {
+		newChan.Reject(ssh.ConnectionFailed, err.Error()) //nolint:errcheck
+		return
+	}
+	defer target.Close() //nolint:errcheck // best effort cleanup
+
+	channel, requests, err := newChan.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close() //nolint:errcheck // best effort cleanup
+	go ssh.DiscardRequests(requests)
+
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(target, channel); done <- struct{}{} }() //nolint:errcheck
+	go func() { io.Copy(channel, target); done <- struct{}{} }() //nolint:errcheck
+	<-done
+}
+
+func (srv *testSSHServer) handleSession(t *testing.T, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close() //nolint:errcheck // best effort cleanup
+	for req := range requests {
+		switch req.Type {
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				req.Reply(false, nil) //nolint:errcheck
+				continue
+			}
+			req.Reply(true, nil) //nolint:errcheck
+			fmt.Fprintf(channel, "ran: %s", payload.Command)
+			_, err := channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+			if err != nil {
+				t.Logf("exit-status: %v", err)
+			}
+			return
+		case "auth-agent-req@openssh.com":
+			req.Reply(true, nil) //nolint:errcheck
+		default:
+			if req.WantReply {
+				req.Reply(false, nil) //nolint:errcheck
+			}
+		}
+	}
+}
+
+// writeTempKey generates a fresh ed25519 keypair and writes the private key,
+// PEM-encoded, to a temp file so SSHConnector can load it via loadSigner.
+func writeTempKey(t *testing.T) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSSHConnectorRun(t *testing.T) {
+	srv := startTestSSHServer(t)
+	keyPath := writeTempKey(t)
+
+	conn, err := NewSSHConnector("user", keyPath, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := conn.Connect(context.Background(), srv.addr, "host", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close() //nolint:errcheck // best effort cleanup
+
+	var out bytes.Buffer
+	if err := sess.Run(context.Background(), "echo hello", &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "ran: echo hello" {
+		t.Errorf("expected %q, got %q", "ran: echo hello", out.String())
+	}
+
+	if sess.Shell() != ShellPOSIX {
+		t.Errorf("expected an ssh session to report ShellPOSIX, got %q", sess.Shell())
+	}
+}
+
+func TestSSHSessionAgentForwardingOncePerSession(t *testing.T) {
+	srv := startTestSSHServer(t)
+	keyPath := writeTempKey(t)
+
+	// no SSH_AUTH_SOCK set: ensureAgentForwarding should fail fast and consistently,
+	// never touching the ssh client, and must not panic/error differently on repeat calls
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	conn, err := NewSSHConnector("user", keyPath, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := conn.Connect(context.Background(), srv.addr, "host", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close() //nolint:errcheck // best effort cleanup
+
+	ss, ok := sess.(*sshSession)
+	if !ok {
+		t.Fatalf("expected *sshSession, got %T", sess)
+	}
+	first := ss.ensureAgentForwarding()
+	second := ss.ensureAgentForwarding()
+	if first == nil || second == nil {
+		t.Fatal("expected ensureAgentForwarding to fail without SSH_AUTH_SOCK")
+	}
+	if first.Error() != second.Error() {
+		t.Errorf("expected ensureAgentForwarding to return the same cached error on every call (sync.Once), got %q then %q", first, second)
+	}
+
+	// Run surfaces the same cached error on every call rather than panicking or
+	// erroring differently the second time round (agent.ForwardToAgent errors if
+	// called twice for the same *ssh.Client, which sync.Once must prevent).
+	var out bytes.Buffer
+	err1 := sess.Run(context.Background(), "echo hi", &out)
+	err2 := sess.Run(context.Background(), "echo hi", &out)
+	if err1 == nil || err2 == nil {
+		t.Fatal("expected Run to surface the agent-forwarding failure")
+	}
+}
+
+// knownHostsLine formats addr/key as a single known_hosts entry.
+func knownHostsLine(addr string, key ssh.PublicKey) string {
+	return fmt.Sprintf("%s %s", addr, string(bytes.TrimSpace(ssh.MarshalAuthorizedKey(key))))
+}
+
+func writeKnownHosts(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSSHConnectorBastionKnownHostsAccepted(t *testing.T) {
+	bastion := startTestSSHServer(t)
+	target := startTestSSHServer(t)
+	keyPath := writeTempKey(t)
+	knownHosts := writeKnownHosts(t, knownHostsLine(bastion.addr, bastion.hostKey.PublicKey()))
+
+	conn, err := NewSSHConnector("user", keyPath, []ProxyHop{{Addr: bastion.addr, User: "user", Key: keyPath, KnownHosts: knownHosts}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sess, err := conn.Connect(context.Background(), target.addr, "target", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close() //nolint:errcheck // best effort cleanup
+
+	var out bytes.Buffer
+	if err := sess.Run(context.Background(), "echo via-bastion", &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "via-bastion") {
+		t.Errorf("expected command to run through the bastion chain, got %q", out.String())
+	}
+}
+
+func TestSSHConnectorBastionKnownHostsMismatchRejected(t *testing.T) {
+	bastion := startTestSSHServer(t)
+	target := startTestSSHServer(t)
+	keyPath := writeTempKey(t)
+
+	other := startTestSSHServer(t) // used only to produce an unrelated host key
+	knownHosts := writeKnownHosts(t, knownHostsLine(bastion.addr, other.hostKey.PublicKey()))
+
+	conn, err := NewSSHConnector("user", keyPath, []ProxyHop{{Addr: bastion.addr, User: "user", Key: keyPath, KnownHosts: knownHosts}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = conn.Connect(context.Background(), target.addr, "target", "", false)
+	if err == nil {
+		t.Fatal("expected connect to fail on a host key mismatch")
+	}
+}
+
+func TestSSHConnectorBastionChain(t *testing.T) {
+	bastion := startTestSSHServer(t)
+	target := startTestSSHServer(t)
+	keyPath := writeTempKey(t)
+
+	conn, err := NewSSHConnector("user", keyPath, []ProxyHop{{Addr: bastion.addr, User: "user", Key: keyPath}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sess, err := conn.Connect(context.Background(), target.addr, "target", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close() //nolint:errcheck // best effort cleanup
+
+	var out bytes.Buffer
+	if err := sess.Run(context.Background(), "echo via-bastion", &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "via-bastion") {
+		t.Errorf("expected command to run through the bastion chain, got %q", out.String())
+	}
+}
@@ -0,0 +1,413 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	winrmResourceURIShell = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/cmd"
+	winrmActionCreate     = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Create"
+	winrmActionDelete     = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Delete"
+	winrmActionCommand    = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Command"
+	winrmActionReceive    = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Receive"
+	winrmActionSignal     = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Signal"
+)
+
+// WinRMConnector connects to Windows hosts over WinRM (HTTP/HTTPS), authenticating
+// with HTTP Basic auth (NTLM is not implemented). Task primitives are translated to
+// PowerShell equivalents by the session so the rest of the runner stays transport-agnostic.
+type WinRMConnector struct {
+	user     string
+	password string
+
+	https      bool
+	port       int
+	insecure   bool // skip TLS verification
+	caCertPath string
+}
+
+// WinRMOption customizes a WinRMConnector.
+type WinRMOption func(*WinRMConnector)
+
+// WithWinRMTLS switches the connector to HTTPS, optionally skipping verification
+// or pinning a CA certificate.
+func WithWinRMTLS(insecureSkipVerify bool, caCertPath string) WinRMOption {
+	return func(c *WinRMConnector) {
+		c.https = true
+		c.insecure = insecureSkipVerify
+		c.caCertPath = caCertPath
+	}
+}
+
+// WithWinRMPort overrides the default WinRM port (5985 plain, 5986 TLS).
+func WithWinRMPort(port int) WinRMOption {
+	return func(c *WinRMConnector) { c.port = port }
+}
+
+// NewWinRMConnector creates a winrm-backed Connector. key is interpreted as a
+// password here (WinRM has no key-based auth), keeping the same user/key call
+// shape as NewSSHConnector so callers don't need to branch.
+func NewWinRMConnector(user, key string, opts ...WinRMOption) (*WinRMConnector, error) {
+	c := &WinRMConnector{user: user, password: key}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.port == 0 {
+		c.port = 5985
+		if c.https {
+			c.port = 5986
+		}
+	}
+	return c, nil
+}
+
+// Connect opens a WinRM session (creating a remote shell) to hostAddr.
+// forwardAgent is ignored: WinRM has no equivalent to ssh-agent forwarding.
+func (w *WinRMConnector) Connect(ctx context.Context, hostAddr, hostName, user string, forwardAgent bool) (Session, error) {
+	if user == "" {
+		user = w.user
+	}
+
+	scheme := "http"
+	if w.https {
+		scheme = "https"
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	if w.https {
+		tlsCfg := &tls.Config{InsecureSkipVerify: w.insecure} //nolint:gosec // explicit opt-in via config
+		if w.caCertPath != "" {
+			pool, err := certPoolFromFile(w.caCertPath)
+			if err != nil {
+				return nil, fmt.Errorf("can't load winrm ca cert: %w", err)
+			}
+			tlsCfg.RootCAs = pool
+		}
+		httpClient.Transport = &http.Transport{TLSClientConfig: tlsCfg}
+	}
+
+	sess := &winrmSession{
+		endpoint: fmt.Sprintf("%s://%s:%d/wsman", scheme, hostAddr, w.port),
+		user:     user,
+		password: w.password,
+		client:   httpClient,
+	}
+	if _, err := sess.ensureShell(ctx); err != nil {
+		return nil, fmt.Errorf("can't connect to %s (%s): %w", hostName, hostAddr, err)
+	}
+	return sess, nil
+}
+
+func certPoolFromFile(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// winrmSession implements Session over the WinRM SOAP protocol: a single shell
+// is created on first use and reused for every command run through it (one
+// Create, then a Command/Receive/Signal exchange per Run), and torn down on Close.
+// Commands are wrapped in powershell.exe -Command so the same task primitives
+// used for ssh (cmd, copy, script, wait) work unmodified on Windows targets.
+type winrmSession struct {
+	endpoint string
+	user     string
+	password string
+	client   *http.Client
+
+	mu      sync.Mutex
+	shellID string
+}
+
+// setAuth applies HTTP Basic auth to req; WinRM's NTLM auth isn't implemented.
+func (w *winrmSession) setAuth(req *http.Request) {
+	req.SetBasicAuth(w.user, w.password)
+}
+
+// do posts a single WS-Management SOAP request and returns its raw response body.
+func (w *winrmSession) do(ctx context.Context, action, shellID, body string) ([]byte, error) {
+	envelope := winrmEnvelope(w.endpoint, action, winrmResourceURIShell, shellID, body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint, strings.NewReader(envelope))
+	if err != nil {
+		return nil, fmt.Errorf("can't build winrm request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/soap+xml;charset=UTF-8")
+	w.setAuth(req)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("winrm request failed: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // best effort cleanup
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("can't read winrm response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("winrm request failed with status %d: %s", resp.StatusCode, data)
+	}
+	return data, nil
+}
+
+// ensureShell creates the remote shell on first call and reuses it afterwards.
+func (w *winrmSession) ensureShell(ctx context.Context) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.shellID != "" {
+		return w.shellID, nil
+	}
+
+	body := `<rsp:Shell xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell">` +
+		`<rsp:InputStreams>stdin</rsp:InputStreams><rsp:OutputStreams>stdout stderr</rsp:OutputStreams></rsp:Shell>`
+	data, err := w.do(ctx, winrmActionCreate, "", body)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		ShellID string `xml:"Body>Shell>ShellId"`
+	}
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("can't parse create-shell response: %w", err)
+	}
+	if resp.ShellID == "" {
+		return "", fmt.Errorf("no shell id in create-shell response")
+	}
+	w.shellID = resp.ShellID
+	return w.shellID, nil
+}
+
+func (w *winrmSession) Run(ctx context.Context, cmd string, out io.Writer) error {
+	shellID, err := w.ensureShell(ctx)
+	if err != nil {
+		return fmt.Errorf("can't open winrm shell: %w", err)
+	}
+
+	cmdID, err := w.runCommand(ctx, shellID, toPowerShell(cmd))
+	if err != nil {
+		return fmt.Errorf("can't start command: %w", err)
+	}
+
+	exitCode, err := w.receiveOutput(ctx, shellID, cmdID, out)
+	if err != nil {
+		return fmt.Errorf("can't read command output: %w", err)
+	}
+	_ = w.signal(ctx, shellID, cmdID) //nolint:errcheck // best effort cleanup, command already finished
+
+	if exitCode != 0 {
+		return fmt.Errorf("command exited with status %d", exitCode)
+	}
+	return nil
+}
+
+func (w *winrmSession) runCommand(ctx context.Context, shellID, cmd string) (string, error) {
+	escaped := &strings.Builder{}
+	if err := xml.EscapeText(escaped, []byte(cmd)); err != nil {
+		return "", fmt.Errorf("can't escape command: %w", err)
+	}
+	body := fmt.Sprintf(`<rsp:CommandLine xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell">`+
+		`<rsp:Command>%s</rsp:Command></rsp:CommandLine>`, escaped.String())
+
+	data, err := w.do(ctx, winrmActionCommand, shellID, body)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		CommandID string `xml:"Body>CommandResponse>CommandId"`
+	}
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("can't parse command response: %w", err)
+	}
+	if resp.CommandID == "" {
+		return "", fmt.Errorf("no command id in response")
+	}
+	return resp.CommandID, nil
+}
+
+// receiveOutput polls Receive until the command reports it's done, writing
+// each decoded stdout/stderr chunk to out as it arrives.
+func (w *winrmSession) receiveOutput(ctx context.Context, shellID, cmdID string, out io.Writer) (int, error) {
+	body := fmt.Sprintf(`<rsp:Receive xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell">`+
+		`<rsp:DesiredStream CommandId="%s">stdout stderr</rsp:DesiredStream></rsp:Receive>`, cmdID)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		default:
+		}
+
+		data, err := w.do(ctx, winrmActionReceive, shellID, body)
+		if err != nil {
+			return 0, err
+		}
+
+		var resp struct {
+			Streams []struct {
+				End     bool   `xml:"End,attr"`
+				Content string `xml:",chardata"`
+			} `xml:"Body>ReceiveResponse>Stream"`
+			CommandState struct {
+				State    string `xml:"State,attr"`
+				ExitCode string `xml:"ExitCode"`
+			} `xml:"Body>ReceiveResponse>CommandState"`
+		}
+		if err := xml.Unmarshal(data, &resp); err != nil {
+			return 0, fmt.Errorf("can't parse receive response: %w", err)
+		}
+
+		for _, stream := range resp.Streams {
+			if stream.Content == "" {
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(stream.Content)
+			if err != nil {
+				return 0, fmt.Errorf("can't decode stream chunk: %w", err)
+			}
+			if _, err := out.Write(decoded); err != nil {
+				return 0, err
+			}
+		}
+
+		if strings.HasSuffix(resp.CommandState.State, "/Done") {
+			exitCode, _ := strconv.Atoi(strings.TrimSpace(resp.CommandState.ExitCode))
+			return exitCode, nil
+		}
+	}
+}
+
+func (w *winrmSession) signal(ctx context.Context, shellID, cmdID string) error {
+	body := fmt.Sprintf(`<rsp:Signal xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell" CommandId="%s">`+
+		`<rsp:Code>http://schemas.microsoft.com/wbem/wsman/1/windows/shell/signal/terminate</rsp:Code></rsp:Signal>`, cmdID)
+	_, err := w.do(ctx, winrmActionSignal, shellID, body)
+	return err
+}
+
+func (w *winrmSession) Upload(ctx context.Context, local, remote string, mkdir bool) error {
+	data, err := readAndEncode(local)
+	if err != nil {
+		return err
+	}
+
+	if mkdir {
+		mkdirCmd := fmt.Sprintf("New-Item -ItemType Directory -Force -Path (Split-Path -Parent '%s') | Out-Null", remote)
+		if err := w.Run(ctx, mkdirCmd, io.Discard); err != nil {
+			return fmt.Errorf("can't create remote dir: %w", err)
+		}
+	}
+
+	// WinRM has no native scp-like transfer; the whole file is base64-encoded
+	// into a single PowerShell one-liner that decodes and writes it. The payload
+	// is not split into chunks, so it all travels in that one command line.
+	writeCmd := fmt.Sprintf(
+		"[IO.File]::WriteAllBytes('%s', [Convert]::FromBase64String('%s'))", remote, data)
+	return w.Run(ctx, writeCmd, io.Discard)
+}
+
+// Shell reports that commands run through this session are interpreted by PowerShell.
+func (w *winrmSession) Shell() ShellKind {
+	return ShellPowerShell
+}
+
+// Close deletes the remote shell, if one was created.
+func (w *winrmSession) Close() error {
+	w.mu.Lock()
+	shellID := w.shellID
+	w.shellID = ""
+	w.mu.Unlock()
+	if shellID == "" {
+		return nil
+	}
+	_, err := w.do(context.Background(), winrmActionDelete, shellID, "")
+	return err
+}
+
+// toPowerShell wraps cmd so the same shell primitives used for ssh targets
+// (cmd, script) execute as PowerShell on Windows. The whole command line is
+// sent to winrm's CreateProcess-style shell, so cmd is quoted with Windows
+// argv escaping, not Go's %q: %q is Go-string escaping and doubles every
+// backslash, corrupting the common case of a Windows path in the command.
+func toPowerShell(cmd string) string {
+	return fmt.Sprintf("powershell.exe -NoProfile -NonInteractive -Command %s", windowsQuoteArg(cmd))
+}
+
+// windowsQuoteArg quotes s as a single Windows command-line argument, following
+// the escaping rules the Microsoft C runtime (and so CreateProcess/powershell.exe)
+// uses to split argv: backslashes are only special immediately before a `"`, where
+// each one doubles and the quote itself is escaped.
+func windowsQuoteArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n\v\"") {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			slashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, slashes*2+1))
+			slashes = 0
+			b.WriteByte('"')
+		default:
+			b.WriteString(strings.Repeat(`\`, slashes))
+			slashes = 0
+			b.WriteByte(s[i])
+		}
+	}
+	b.WriteString(strings.Repeat(`\`, slashes*2))
+	b.WriteByte('"')
+	return b.String()
+}
+
+func readAndEncode(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("can't read local file %s: %w", path, err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// winrmEnvelope builds the SOAP envelope + WS-Addressing/WS-Management headers
+// shared by every request against endpoint: Create/Command/Receive/Signal/Delete
+// all differ only in action and body, and all but Create carry a ShellId selector.
+func winrmEnvelope(endpoint, action, resourceURI, shellID, body string) string {
+	var selectorSet string
+	if shellID != "" {
+		selectorSet = fmt.Sprintf(`<w:SelectorSet><w:Selector Name="ShellId">%s</w:Selector></w:SelectorSet>`, shellID)
+	}
+	return fmt.Sprintf(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"
+  xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing"
+  xmlns:w="http://schemas.dmtf.org/wbem/wsman/1/wsman.xsd">
+  <s:Header>
+    <wsa:To>%s</wsa:To>
+    <wsa:Action>%s</wsa:Action>
+    <wsa:ReplyTo><wsa:Address mustUnderstand="true">http://schemas.xmlsoap.org/ws/2004/08/addressing/role/anonymous</wsa:Address></wsa:ReplyTo>
+    <w:ResourceURI mustUnderstand="true">%s</w:ResourceURI>
+    <w:OperationTimeout>PT60S</w:OperationTimeout>
+    %s
+  </s:Header>
+  <s:Body>%s</s:Body>
+</s:Envelope>`, endpoint, action, resourceURI, selectorSet, body)
+}
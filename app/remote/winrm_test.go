@@ -0,0 +1,132 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeWinRMServer answers the WS-Management Create/Command/Receive/Signal/Delete
+// exchange winrmSession drives, enough to run one command end to end.
+func fakeWinRMServer(t *testing.T, wantCommand string) *httptest.Server {
+	t.Helper()
+	state := "Running"
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body := string(data)
+		switch {
+		case strings.Contains(body, "transfer/Create"):
+			fmt.Fprint(w, `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body>`+
+				`<rsp:Shell xmlns:rsp="x"><rsp:ShellId>SHELL1</rsp:ShellId></rsp:Shell></s:Body></s:Envelope>`)
+		case strings.Contains(body, "shell/Command"):
+			if wantCommand != "" && !strings.Contains(body, wantCommand) {
+				t.Errorf("expected command line to contain %q, got request body %q", wantCommand, body)
+			}
+			fmt.Fprint(w, `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body>`+
+				`<rsp:CommandResponse xmlns:rsp="x"><rsp:CommandId>CMD1</rsp:CommandId></rsp:CommandResponse></s:Body></s:Envelope>`)
+		case strings.Contains(body, "shell/Receive"):
+			if state == "Running" {
+				state = "Done"
+				fmt.Fprint(w, `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><rsp:ReceiveResponse xmlns:rsp="x">`+
+					`<rsp:Stream Name="stdout">aGVsbG8=</rsp:Stream>`+
+					`<rsp:CommandState State="http://schemas.microsoft.com/wbem/wsman/1/windows/shell/CommandState/Running"/>`+
+					`</rsp:ReceiveResponse></s:Body></s:Envelope>`)
+			} else {
+				fmt.Fprint(w, `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><rsp:ReceiveResponse xmlns:rsp="x">`+
+					`<rsp:CommandState State="http://schemas.microsoft.com/wbem/wsman/1/windows/shell/CommandState/Done">`+
+					`<rsp:ExitCode>0</rsp:ExitCode></rsp:CommandState></rsp:ReceiveResponse></s:Body></s:Envelope>`)
+			}
+		case strings.Contains(body, "shell/Signal"), strings.Contains(body, "transfer/Delete"):
+			fmt.Fprint(w, `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body></s:Body></s:Envelope>`)
+		default:
+			t.Fatalf("unexpected winrm request: %s", body)
+		}
+	}))
+}
+
+func connectToFake(t *testing.T, srv *httptest.Server) Session {
+	t.Helper()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := NewWinRMConnector("user", "pass", WithWinRMPort(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := conn.Connect(context.Background(), u.Hostname(), "host", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+func TestWinRMSessionRun(t *testing.T) {
+	srv := fakeWinRMServer(t, "")
+	defer srv.Close()
+
+	sess := connectToFake(t, srv)
+	defer sess.Close() //nolint:errcheck // best effort cleanup
+
+	var out strings.Builder
+	if err := sess.Run(context.Background(), "echo hello", &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "hello" {
+		t.Errorf("expected %q, got %q", "hello", out.String())
+	}
+
+	if sess.Shell() != ShellPowerShell {
+		t.Errorf("expected a winrm session to report ShellPowerShell, got %q", sess.Shell())
+	}
+}
+
+func TestWinRMSessionRunQuotesBackslashes(t *testing.T) {
+	// a %q-based implementation would double every backslash in a Windows path,
+	// corrupting the command actually executed
+	srv := fakeWinRMServer(t, `C:\Program Files\app.exe`)
+	defer srv.Close()
+
+	sess := connectToFake(t, srv)
+	defer sess.Close() //nolint:errcheck // best effort cleanup
+
+	if err := sess.Run(context.Background(), `C:\Program Files\app.exe`, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestToPowerShellWrapsCommand(t *testing.T) {
+	got := toPowerShell(`echo "hi"`)
+	want := `powershell.exe -NoProfile -NonInteractive -Command "echo \"hi\""`
+	if got != want {
+		t.Errorf("toPowerShell(%q) = %q, want %q", `echo "hi"`, got, want)
+	}
+}
+
+func TestWindowsQuoteArg(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{`simple`, `simple`},
+		{`a b`, `"a b"`},
+		{`C:\path\to\file`, `C:\path\to\file`},
+		{`C:\dir with space\file`, `"C:\dir with space\file"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`trailing\`, `trailing\`},
+		{`trailing\` + ` `, `"trailing\ "`},
+		{``, `""`},
+	}
+	for _, c := range cases {
+		if got := windowsQuoteArg(c.in); got != c.want {
+			t.Errorf("windowsQuoteArg(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
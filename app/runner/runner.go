@@ -0,0 +1,241 @@
+// Package runner executes tasks from a config.PlayBook against one or more
+// remote hosts, fanning out commands through a remote.Connector.
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-pkgz/lgr"
+
+	"github.com/umputun/simplotask/app/config"
+	"github.com/umputun/simplotask/app/remote"
+	"github.com/umputun/simplotask/app/secrets"
+)
+
+// ConnectorFactory creates a Connector for a target, allowing Process to pick ssh
+// or winrm (or any other transport) and a bastion chain based on the target's config.
+type ConnectorFactory func(target config.Target) (remote.Connector, error)
+
+// Process runs a single named task against a named target.
+type Process struct {
+	Concurrency  int
+	Connector    remote.Connector // used when ConnectorFor is nil, i.e. single connector for all hosts
+	ConnectorFor ConnectorFactory
+	Config       *config.PlayBook
+	Only         []string
+	Skip         []string
+	Out          io.Writer         // per-host prefixed command output, defaults to os.Stdout
+	VarsOverride map[string]string // wins over both the playbook's and the task's own vars
+}
+
+func (p *Process) out() io.Writer {
+	if p.Out == nil {
+		return os.Stdout
+	}
+	return p.Out
+}
+
+// Run executes taskName against the hosts of targetName, honoring Concurrency.
+func (p *Process) Run(ctx context.Context, taskName, targetName string) error {
+	task, ok := p.Config.Tasks[taskName]
+	if !ok {
+		return fmt.Errorf("task %q not found", taskName)
+	}
+
+	hosts, err := p.Config.TargetHosts(targetName)
+	if err != nil {
+		return fmt.Errorf("can't resolve target %q: %w", targetName, err)
+	}
+
+	sem := make(chan struct{}, p.Concurrency)
+	var wg sync.WaitGroup
+	errs := make(chan error, len(hosts))
+
+	for _, host := range hosts {
+		host := host
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := p.runOnHost(ctx, host, targetName, task); err != nil {
+				errs <- fmt.Errorf("%s: %w", host, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connectorForHost picks the connector for targetName, using the target's
+// config when a ConnectorFactory is set, falling back to the single Connector.
+func (p *Process) connectorForHost(targetName string) (remote.Connector, error) {
+	if p.ConnectorFor == nil {
+		return p.Connector, nil
+	}
+	return p.ConnectorFor(p.Config.Targets[targetName])
+}
+
+func (p *Process) runOnHost(ctx context.Context, host, targetName string, task config.Task) error {
+	conn, err := p.connectorForHost(targetName)
+	if err != nil {
+		return fmt.Errorf("can't get connector: %w", err)
+	}
+
+	sess, err := conn.Connect(ctx, host, host, task.User, task.ForwardAgent)
+	if err != nil {
+		return fmt.Errorf("can't connect: %w", err)
+	}
+	defer sess.Close() //nolint:errcheck // best effort cleanup
+
+	out := &hostWriter{host: host, w: p.out()}
+	vars := p.Config.MergedVars(task.Name)
+	for k, v := range p.VarsOverride {
+		vars[k] = v
+	}
+	for _, cmd := range task.Commands {
+		if p.skip(cmd.Name) {
+			continue
+		}
+		if err := p.runCmd(ctx, sess, cmd, vars, out); err != nil {
+			return fmt.Errorf("%s: %w", cmd.Name, err)
+		}
+	}
+	return nil
+}
+
+// runCmd executes a single task primitive, injecting vars (which may carry
+// decrypted vault secrets) as env for cmd/script commands.
+func (p *Process) runCmd(ctx context.Context, sess remote.Session, cmd config.Cmd, vars map[string]string, out io.Writer) error {
+	switch {
+	case cmd.Copy != nil:
+		return sess.Upload(ctx, cmd.Copy.Src, cmd.Copy.Dst, cmd.Copy.Mkdir)
+	case cmd.Script != "":
+		return p.runShell(ctx, sess, cmd.Script, vars, out)
+	case cmd.Wait != nil:
+		return waitFor(ctx, sess, cmd.Wait)
+	default:
+		return p.runShell(ctx, sess, cmd.Cmd, vars, out)
+	}
+}
+
+// runShell prepends vars as env exports and logs the command with any secret
+// values scrubbed, so vault-decrypted values never hit stdout/stderr logs.
+func (p *Process) runShell(ctx context.Context, sess remote.Session, cmdLine string, vars map[string]string, out io.Writer) error {
+	full := withEnv(sess.Shell(), cmdLine, vars)
+	lgr.Printf("[DEBUG] run %q", secrets.Scrub(full, secretValues(vars)))
+	return sess.Run(ctx, full, out)
+}
+
+// hostWriter prefixes every write with the host it came from, so output from
+// concurrent hosts sharing a single sink (e.g. a job's log broadcaster) stays attributable.
+type hostWriter struct {
+	host string
+	w    io.Writer
+}
+
+func (h *hostWriter) Write(p []byte) (int, error) {
+	if _, err := fmt.Fprintf(h.w, "[%s] ", h.host); err != nil {
+		return 0, err
+	}
+	return h.w.Write(p)
+}
+
+// withEnv prepends one env-assignment statement per var ahead of cmdLine, in
+// the dialect shell expects, sorted for a deterministic, diff-friendly command
+// line. Values are quoted as shell literals, not Go-quoted: %q leaves `$` and
+// backticks untouched, letting a var value like "x$(touch /tmp/pwned)" run as
+// real command substitution once the remote shell sees it.
+func withEnv(shell remote.ShellKind, cmdLine string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return cmdLine
+	}
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		switch shell {
+		case remote.ShellPowerShell:
+			fmt.Fprintf(&b, "$env:%s=%s; ", k, powerShellQuote(vars[k]))
+		default:
+			fmt.Fprintf(&b, "export %s=%s; ", k, posixQuote(vars[k]))
+		}
+	}
+	b.WriteString(cmdLine)
+	return b.String()
+}
+
+// posixQuote wraps s in single quotes for a POSIX shell, which take every byte
+// literally except a single quote itself: each one is closed out, escaped, and reopened.
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powerShellQuote wraps s in single quotes for PowerShell, which takes every
+// byte literally except a single quote itself: each one is doubled.
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func secretValues(vars map[string]string) []string {
+	res := make([]string, 0, len(vars)*3)
+	for _, v := range vars {
+		if v == "" {
+			continue
+		}
+		// a value holding a quote reaches the command line only in its escaped
+		// form, so the quoted forms are scrubbed first, then the raw value
+		res = append(res, posixQuote(v), powerShellQuote(v), v)
+	}
+	return res
+}
+
+func (p *Process) skip(name string) bool {
+	if len(p.Only) > 0 {
+		for _, o := range p.Only {
+			if o == name {
+				return false
+			}
+		}
+		return true
+	}
+	for _, s := range p.Skip {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+func waitFor(ctx context.Context, sess remote.Session, w *config.Wait) error {
+	deadline, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+	for {
+		if err := sess.Run(deadline, w.Command, io.Discard); err == nil {
+			return nil
+		}
+		select {
+		case <-deadline.Done():
+			return fmt.Errorf("wait timed out: %w", deadline.Err())
+		case <-time.After(w.CheckDuration):
+		}
+	}
+}
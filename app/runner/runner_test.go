@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/umputun/simplotask/app/remote"
+)
+
+func TestWithEnvPosixQuotesShellMetacharacters(t *testing.T) {
+	// a naive %q-based implementation renders $(...) inside Go-escaped double
+	// quotes, where a POSIX shell still expands it as command substitution; a
+	// single-quoted literal (what we assert on below) makes it inert text instead
+	full := withEnv(remote.ShellPOSIX, "echo done", map[string]string{
+		"FOO": "x$(touch /tmp/pwned)",
+	})
+
+	if !strings.Contains(full, `export FOO='x$(touch /tmp/pwned)'`) {
+		t.Fatalf("expected the value wrapped as a single-quoted literal, got %q", full)
+	}
+	if !strings.HasSuffix(full, "echo done") {
+		t.Errorf("expected cmdLine appended at the end, got %q", full)
+	}
+}
+
+func TestWithEnvPosixEscapesEmbeddedQuote(t *testing.T) {
+	full := withEnv(remote.ShellPOSIX, "true", map[string]string{"FOO": "it's a test"})
+	if !strings.Contains(full, `export FOO='it'\''s a test'`) {
+		t.Errorf("expected embedded single quote escaped POSIX-style, got %q", full)
+	}
+}
+
+func TestWithEnvPowerShell(t *testing.T) {
+	full := withEnv(remote.ShellPowerShell, "Get-Date", map[string]string{"FOO": "it's a test"})
+	if !strings.Contains(full, `$env:FOO='it''s a test'`) {
+		t.Errorf("expected embedded single quote doubled PowerShell-style, got %q", full)
+	}
+	if !strings.HasSuffix(full, "Get-Date") {
+		t.Errorf("expected cmdLine appended at the end, got %q", full)
+	}
+}
+
+func TestWithEnvNoVars(t *testing.T) {
+	if got := withEnv(remote.ShellPOSIX, "echo hi", nil); got != "echo hi" {
+		t.Errorf("expected cmdLine unchanged with no vars, got %q", got)
+	}
+}
+
+func TestWithEnvDeterministicOrder(t *testing.T) {
+	vars := map[string]string{"B": "2", "A": "1", "C": "3"}
+	full := withEnv(remote.ShellPOSIX, "cmd", vars)
+	if strings.Index(full, "A=") > strings.Index(full, "B=") || strings.Index(full, "B=") > strings.Index(full, "C=") {
+		t.Errorf("expected vars sorted alphabetically, got %q", full)
+	}
+}
+
+// fakeSession is a minimal remote.Session recording the last command it ran,
+// used to verify var injection end-to-end without a real transport.
+type fakeSession struct {
+	shell   remote.ShellKind
+	lastCmd string
+}
+
+func (f *fakeSession) Run(_ context.Context, cmd string, _ io.Writer) error {
+	f.lastCmd = cmd
+	return nil
+}
+func (f *fakeSession) Upload(context.Context, string, string, bool) error { return nil }
+func (f *fakeSession) Close() error                                       { return nil }
+func (f *fakeSession) Shell() remote.ShellKind                            { return f.shell }
+
+func TestProcessRunShellUsesSessionShell(t *testing.T) {
+	p := &Process{}
+	sess := &fakeSession{shell: remote.ShellPowerShell}
+
+	if err := p.runShell(context.Background(), sess, "Get-Date", map[string]string{"FOO": "bar"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sess.lastCmd, "$env:FOO='bar'") {
+		t.Errorf("expected powershell-style env assignment, got %q", sess.lastCmd)
+	}
+	if strings.Contains(sess.lastCmd, "export ") {
+		t.Errorf("posix export leaked into a powershell session's command: %q", sess.lastCmd)
+	}
+}
@@ -0,0 +1,155 @@
+// Package secrets lets spt.yml commit encrypted values (passwords, tokens, keys)
+// instead of plaintext, decrypting them at load time with a key supplied out of band.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// header identifies an encrypted value and its format version, mirroring the
+// way CI systems tag encrypted secrets committed to a repo.
+const header = "$SPOT_VAULT;AES256;v1\n"
+
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLen      = 16
+)
+
+// Vault encrypts and decrypts values with a key derived from a single passphrase.
+type Vault struct {
+	passphrase []byte
+}
+
+// New creates a Vault from a raw passphrase, as resolved from --vault-key,
+// $SPOT_VAULT_KEY or a keyfile by the caller.
+func New(passphrase string) (*Vault, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("vault key is empty")
+	}
+	return &Vault{passphrase: []byte(passphrase)}, nil
+}
+
+// NewFromKeyFile creates a Vault from a passphrase stored in a file, trimming
+// surrounding whitespace/newlines as is conventional for keyfiles.
+func NewFromKeyFile(path string) (*Vault, error) {
+	key, err := ReadKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(key)
+}
+
+// ReadKeyFile reads a vault passphrase from path, trimming surrounding whitespace.
+func ReadKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("can't read vault keyfile %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Encrypt turns plaintext into the "$SPOT_VAULT;AES256;v1\n<base64>" form stored
+// inline in spt.yml, deriving a fresh per-value key from a random salt and sealing
+// with AES-GCM under a random nonce.
+func (v *Vault) Encrypt(plaintext string) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", fmt.Errorf("can't generate salt: %w", err)
+	}
+
+	key, err := scrypt.Key(v.passphrase, salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return "", fmt.Errorf("can't derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("can't init cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("can't init gcm: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("can't generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nil, nonce, []byte(plaintext), nil)
+
+	// payload layout: salt || nonce || ciphertext, all base64-encoded together
+	payload := append(append(salt, nonce...), sealed...) //nolint:gocritic // intentional concat into a new slice
+	return header + base64.StdEncoding.EncodeToString(payload), nil
+}
+
+// Decrypt reverses Encrypt. IsEncrypted values pass through this; plaintext
+// values passed in error out, since callers are expected to check IsEncrypted first.
+func (v *Vault) Decrypt(encoded string) (string, error) {
+	if !IsEncrypted(encoded) {
+		return "", fmt.Errorf("not a vault-encrypted value")
+	}
+	payload, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encoded, header))
+	if err != nil {
+		return "", fmt.Errorf("can't decode vault payload: %w", err)
+	}
+
+	if len(payload) < saltLen {
+		return "", fmt.Errorf("vault payload too short")
+	}
+	salt, rest := payload[:saltLen], payload[saltLen:]
+
+	key, err := scrypt.Key(v.passphrase, salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return "", fmt.Errorf("can't derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("can't init cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("can't init gcm: %w", err)
+	}
+
+	if len(rest) < gcm.NonceSize() {
+		return "", fmt.Errorf("vault payload too short")
+	}
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("can't decrypt vault value: %w", err)
+	}
+	return string(plain), nil
+}
+
+// IsEncrypted reports whether s is a vault-encrypted value.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, header)
+}
+
+// Scrub replaces any encrypted or decrypted secret values appearing in s with
+// a placeholder, so logged command lines never leak them.
+func Scrub(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		s = strings.ReplaceAll(s, secret, "****")
+	}
+	return s
+}
@@ -0,0 +1,112 @@
+package secrets
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVaultEncryptDecryptRoundTrip(t *testing.T) {
+	v, err := New("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := v.Encrypt("super-secret-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(enc) {
+		t.Fatalf("encrypted value doesn't carry the vault header: %q", enc)
+	}
+
+	dec, err := v.Decrypt(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec != "super-secret-value" {
+		t.Errorf("expected decrypted value %q, got %q", "super-secret-value", dec)
+	}
+}
+
+func TestVaultEncryptIsRandomized(t *testing.T) {
+	v, err := New("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := v.Encrypt("same-plaintext")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := v.Encrypt("same-plaintext")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Error("two encryptions of the same plaintext produced identical ciphertext, salt/nonce not randomized")
+	}
+}
+
+func TestVaultDecryptWrongPassphrase(t *testing.T) {
+	v, err := New("right-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := v.Encrypt("value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrong, err := New("wrong-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrong.Decrypt(enc); err == nil {
+		t.Error("expected decryption with the wrong passphrase to fail")
+	}
+}
+
+func TestVaultDecryptNotEncrypted(t *testing.T) {
+	v, err := New("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Decrypt("plaintext"); err == nil {
+		t.Error("expected Decrypt to reject a value without the vault header")
+	}
+}
+
+func TestNewEmptyPassphrase(t *testing.T) {
+	if _, err := New(""); err == nil {
+		t.Error("expected New to reject an empty passphrase")
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	if IsEncrypted("plain string") {
+		t.Error("plain string reported as encrypted")
+	}
+	if !IsEncrypted(header + "abc") {
+		t.Error("value with the vault header not reported as encrypted")
+	}
+}
+
+func TestScrub(t *testing.T) {
+	line := "curl -H 'Authorization: Bearer abc123' -d 'pw=hunter2' https://example.com"
+	scrubbed := Scrub(line, []string{"abc123", "hunter2"})
+
+	if strings.Contains(scrubbed, "abc123") || strings.Contains(scrubbed, "hunter2") {
+		t.Errorf("secrets leaked in scrubbed output: %q", scrubbed)
+	}
+	if !strings.Contains(scrubbed, "****") {
+		t.Errorf("expected placeholder in scrubbed output: %q", scrubbed)
+	}
+}
+
+func TestScrubIgnoresEmptySecret(t *testing.T) {
+	// an empty secret must be skipped: ReplaceAll with an empty old string would
+	// insert the placeholder between every character and mangle the string
+	if got := Scrub("hello world", []string{""}); got != "hello world" {
+		t.Errorf("expected unchanged string, got %q", got)
+	}
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Status values a Job moves through over its lifetime.
+const (
+	StatusRunning  = "running"
+	StatusDone     = "done"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+)
+
+// Job tracks one triggered run: its request, current status and output.
+// status/errMsg/finishedAt are mutated from the job's run goroutine (finish)
+// and from the HTTP DELETE handler (Cancel) while GET handlers marshal the
+// job concurrently, so all access to them goes through mu.
+type Job struct {
+	ID        string    `json:"id"`
+	Task      string    `json:"task"`
+	Target    string    `json:"target"`
+	StartedAt time.Time `json:"started_at"`
+
+	mu         sync.Mutex
+	status     string
+	errMsg     string
+	finishedAt time.Time
+
+	cancel func()
+	log    *broadcaster
+}
+
+func newJob(id, task, target string, cancel func()) *Job {
+	return &Job{
+		ID:        id,
+		Task:      task,
+		Target:    target,
+		status:    StatusRunning,
+		StartedAt: time.Now(),
+		cancel:    cancel,
+		log:       newBroadcaster(),
+	}
+}
+
+func (j *Job) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finishedAt = time.Now()
+	switch {
+	case err == nil:
+		j.status = StatusDone
+	case j.status == StatusCanceled:
+		// already marked canceled by Cancel, keep it
+	default:
+		j.status = StatusFailed
+		j.errMsg = err.Error()
+	}
+	j.log.close()
+}
+
+// Cancel stops the job's run and marks it canceled.
+func (j *Job) Cancel() {
+	j.mu.Lock()
+	j.status = StatusCanceled
+	j.mu.Unlock()
+	j.cancel()
+}
+
+// Status reports the job's current status, safe for concurrent use.
+func (j *Job) Status() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// jobView is the JSON shape of a Job, snapshotted under lock so MarshalJSON
+// never races with finish/Cancel writing to the same fields.
+type jobView struct {
+	ID         string    `json:"id"`
+	Task       string    `json:"task"`
+	Target     string    `json:"target"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, copying the mutable fields under mu
+// before encoding so concurrent finish/Cancel calls can't race with it.
+func (j *Job) MarshalJSON() ([]byte, error) {
+	j.mu.Lock()
+	view := jobView{
+		ID: j.ID, Task: j.Task, Target: j.Target, StartedAt: j.StartedAt,
+		Status: j.status, Error: j.errMsg, FinishedAt: j.finishedAt,
+	}
+	j.mu.Unlock()
+	return json.Marshal(view)
+}
+
+// broadcaster fans a single writer out to any number of live subscribers
+// (for GET /jobs/{id}/logs?follow=true) while retaining the full history for
+// subscribers that join after the job already produced output.
+type broadcaster struct {
+	mu     sync.Mutex
+	buf    []byte
+	subs   map[chan []byte]struct{}
+	closed bool
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subs: map[chan []byte]struct{}{}}
+}
+
+// Write implements io.Writer, so a broadcaster can be used as runner.Process.Out.
+func (b *broadcaster) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cp := append([]byte(nil), p...)
+	b.buf = append(b.buf, cp...)
+	for ch := range b.subs {
+		select {
+		case ch <- cp:
+		default: // slow subscriber, drop rather than block the run
+		}
+	}
+	return len(p), nil
+}
+
+func (b *broadcaster) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		close(ch)
+	}
+	b.subs = map[chan []byte]struct{}{}
+}
+
+// subscribe returns the history collected so far and, if the job is still
+// running, a channel of subsequent writes. The channel is nil once closed.
+func (b *broadcaster) subscribe() ([]byte, chan []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	history := append([]byte(nil), b.buf...)
+	if b.closed {
+		return history, nil
+	}
+	ch := make(chan []byte, 64)
+	b.subs[ch] = struct{}{}
+	return history, ch
+}
+
+func (b *broadcaster) unsubscribe(ch chan []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, ch)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// TestJobConcurrentAccess exercises finish/Cancel racing against MarshalJSON,
+// the scenario that used to trip -race before Job's fields were guarded by a mutex.
+func TestJobConcurrentAccess(t *testing.T) {
+	j := newJob("1", "deploy", "default", func() {})
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		j.finish(errors.New("boom"))
+	}()
+	go func() {
+		defer wg.Done()
+		j.Cancel()
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if _, err := json.Marshal(j); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	status := j.Status()
+	if status != StatusDone && status != StatusFailed && status != StatusCanceled {
+		t.Fatalf("unexpected status %q", status)
+	}
+}
+
+func TestJobCancelMarksStatus(t *testing.T) {
+	canceled := false
+	j := newJob("1", "deploy", "default", func() { canceled = true })
+	j.Cancel()
+	if j.Status() != StatusCanceled {
+		t.Fatalf("expected status %q, got %q", StatusCanceled, j.Status())
+	}
+	if !canceled {
+		t.Fatal("expected cancel func to run")
+	}
+}
+
+func TestJobFinishKeepsCanceledStatus(t *testing.T) {
+	j := newJob("1", "deploy", "default", func() {})
+	j.Cancel()
+	j.finish(errors.New("context canceled"))
+	if j.Status() != StatusCanceled {
+		t.Fatalf("expected canceled status to stick, got %q", j.Status())
+	}
+}
@@ -0,0 +1,315 @@
+// Package server exposes spot's task runner over a small REST API, so tasks can
+// be triggered and followed remotely instead of only from an interactive CLI run.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-pkgz/lgr"
+
+	"github.com/umputun/simplotask/app/config"
+	"github.com/umputun/simplotask/app/runner"
+)
+
+// Server runs runner.Process jobs on demand and tracks their status/output in memory.
+type Server struct {
+	Addr        string
+	TLSCert     string
+	TLSKey      string
+	Token       string // if set, requests must carry "Authorization: Bearer <Token>"
+	HistoryCap  int    // max number of completed jobs kept around, 0 means unlimited
+	Concurrency int    // per-job host concurrency, forwarded to runner.Process
+
+	Config       *config.PlayBook
+	ConnectorFor runner.ConnectorFactory
+
+	poolSize int
+	pool     chan struct{}
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	order   []string // job IDs in creation order, for HistoryCap eviction
+	jobSeq  int
+}
+
+// New creates a Server ready to ListenAndServe. poolSize bounds how many jobs run concurrently.
+func New(conf *config.PlayBook, connFor runner.ConnectorFactory, poolSize int) *Server {
+	if poolSize <= 0 {
+		poolSize = 4
+	}
+	return &Server{
+		Config:       conf,
+		ConnectorFor: connFor,
+		poolSize:     poolSize,
+		pool:         make(chan struct{}, poolSize),
+		jobs:         map[string]*Job{},
+	}
+}
+
+// RunRequest is the JSON body of POST /tasks/{name}/run.
+type RunRequest struct {
+	Target     string            `json:"target"`
+	Hosts      []string          `json:"hosts"`
+	Only       []string          `json:"only"`
+	Skip       []string          `json:"skip"`
+	Concurrent int               `json:"concurrent"`
+	Vars       map[string]string `json:"vars"`
+}
+
+// ListenAndServe starts the HTTP (or HTTPS, if TLSCert/TLSKey are set) server
+// and blocks until ctx is canceled or the server fails.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.Addr,
+		Handler:           s.auth(s.routes()),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if s.TLSCert != "" || s.TLSKey != "" {
+			errCh <- srv.ListenAndServeTLS(s.TLSCert, s.TLSKey)
+			return
+		}
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutCtx)
+	case err := <-errCh:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	}
+}
+
+func (s *Server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		hdr := r.Header.Get("Authorization")
+		if hdr != "Bearer "+s.Token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tasks/", s.handleTaskRun) // POST /tasks/{name}/run
+	mux.HandleFunc("/jobs", s.handleJobsList)  // GET /jobs
+	mux.HandleFunc("/jobs/", s.handleJobByID)  // GET|DELETE /jobs/{id}, GET /jobs/{id}/logs
+	return mux
+}
+
+func (s *Server) handleTaskRun(w http.ResponseWriter, r *http.Request) {
+	// path: /tasks/{name}/run
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if r.Method != http.MethodPost || len(parts) != 3 || parts[2] != "run" {
+		http.NotFound(w, r)
+		return
+	}
+	taskName := parts[1]
+
+	var req RunRequest
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err.Error() != "EOF" {
+			http.Error(w, fmt.Sprintf("bad request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Target == "" {
+		req.Target = "default"
+	}
+	if req.Concurrent <= 0 {
+		req.Concurrent = s.Concurrency
+	}
+
+	job, err := s.startJob(taskName, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": job.ID})
+}
+
+func (s *Server) handleJobsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	res := make([]*Job, 0, len(s.jobs))
+	for _, id := range s.order {
+		res = append(res, s.jobs[id])
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *Server) handleJobByID(w http.ResponseWriter, r *http.Request) {
+	// path: /jobs/{id} or /jobs/{id}/logs
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 {
+		http.NotFound(w, r)
+		return
+	}
+	job, ok := s.job(parts[1])
+	if !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case len(parts) == 3 && parts[2] == "logs" && r.Method == http.MethodGet:
+		s.streamLogs(w, r, job)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(job)
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		job.Cancel()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) streamLogs(w http.ResponseWriter, r *http.Request, job *Job) {
+	follow := r.URL.Query().Get("follow") == "true"
+
+	history, live := job.log.subscribe()
+	if live != nil {
+		defer job.log.unsubscribe(live)
+	}
+
+	flusher, canFlush := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(history)
+	if canFlush {
+		flusher.Flush()
+	}
+
+	if !follow || live == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case chunk, ok := <-live:
+			if !ok {
+				return
+			}
+			_, _ = w.Write(chunk)
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func (s *Server) startJob(taskName string, req RunRequest) (*Job, error) {
+	if _, ok := s.Config.Tasks[taskName]; !ok {
+		return nil, fmt.Errorf("task %q not found", taskName)
+	}
+
+	conf := *s.Config // shallow copy, Targets map replaced below if hosts were overridden
+	if len(req.Hosts) > 0 {
+		targets := map[string]config.Target{}
+		for k, v := range s.Config.Targets {
+			targets[k] = v
+		}
+		existing := s.Config.Targets[req.Target] // preserve any declared Type/Proxy, override only Hosts
+		existing.Name, existing.Hosts = req.Target, req.Hosts
+		targets[req.Target] = existing
+		conf.Targets = targets
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.mu.Lock()
+	s.jobSeq++
+	id := strconv.Itoa(s.jobSeq)
+	job := newJob(id, taskName, req.Target, cancel)
+	s.jobs[id] = job
+	s.order = append(s.order, id)
+	s.evictLocked()
+	s.mu.Unlock()
+
+	go s.runJob(ctx, job, &conf, taskName, req)
+	return job, nil
+}
+
+func (s *Server) runJob(ctx context.Context, job *Job, conf *config.PlayBook, taskName string, req RunRequest) {
+	s.pool <- struct{}{}
+	defer func() { <-s.pool }()
+
+	proc := runner.Process{
+		Concurrency:  req.Concurrent,
+		ConnectorFor: s.ConnectorFor,
+		Config:       conf,
+		Only:         req.Only,
+		Skip:         req.Skip,
+		Out:          job.log,
+		VarsOverride: req.Vars,
+	}
+
+	err := proc.Run(ctx, taskName, req.Target)
+	if err != nil {
+		lgr.Printf("[WARN] job %s failed: %v", job.ID, err)
+	}
+	job.finish(err)
+}
+
+func (s *Server) job(id string) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	return j, ok
+}
+
+// evictLocked drops the oldest finished jobs once history exceeds HistoryCap.
+// Callers must hold s.mu.
+func (s *Server) evictLocked() {
+	if s.HistoryCap <= 0 {
+		return
+	}
+	finished := 0
+	for _, id := range s.order {
+		if s.jobs[id].Status() != StatusRunning {
+			finished++
+		}
+	}
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if finished > s.HistoryCap && s.jobs[id].Status() != StatusRunning { // don't evict jobs still in flight
+			delete(s.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/umputun/simplotask/app/config"
+	"github.com/umputun/simplotask/app/remote"
+)
+
+// fakeSession records every command it's asked to Run, so tests can inspect
+// exactly what a task produced without a real transport.
+type fakeSession struct {
+	mu  sync.Mutex
+	ran []string
+}
+
+func (f *fakeSession) Run(_ context.Context, cmd string, _ io.Writer) error {
+	f.mu.Lock()
+	f.ran = append(f.ran, cmd)
+	f.mu.Unlock()
+	return nil
+}
+func (f *fakeSession) Upload(context.Context, string, string, bool) error { return nil }
+func (f *fakeSession) Close() error                                       { return nil }
+func (f *fakeSession) Shell() remote.ShellKind                            { return remote.ShellPOSIX }
+
+func (f *fakeSession) commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.ran...)
+}
+
+type fakeConnector struct{ sess *fakeSession }
+
+func (c *fakeConnector) Connect(context.Context, string, string, string, bool) (remote.Session, error) {
+	return c.sess, nil
+}
+
+func testPlayBook() *config.PlayBook {
+	return &config.PlayBook{
+		Targets: map[string]config.Target{"default": {Name: "default", Hosts: []string{"h1"}}},
+		Tasks: map[string]config.Task{
+			"deploy": {Name: "deploy", Commands: []config.Cmd{{Name: "run", Cmd: "echo hi"}}},
+		},
+	}
+}
+
+// waitForJobDone polls until the job finishes or t deadline expires.
+func waitForJobDone(t *testing.T, srv *Server, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := srv.job(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if job.Status() != StatusRunning {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s never finished", id)
+	return nil
+}
+
+func TestHandleTaskRunInjectsVarsSafely(t *testing.T) {
+	sess := &fakeSession{}
+	srv := New(testPlayBook(), func(config.Target) (remote.Connector, error) {
+		return &fakeConnector{sess: sess}, nil
+	}, 1)
+	srv.Concurrency = 1 // runner.Process's host semaphore is sized off this; 0 would deadlock
+
+	body, err := json.Marshal(RunRequest{Target: "default", Vars: map[string]string{
+		"FOO": "x$(touch /tmp/pwned)",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks/deploy/run", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct{ ID string }
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	job := waitForJobDone(t, srv, resp.ID)
+	if job.Status() != StatusDone {
+		t.Fatalf("expected job to succeed, got status %q", job.Status())
+	}
+
+	cmds := sess.commands()
+	if len(cmds) != 1 {
+		t.Fatalf("expected exactly 1 command run, got %d: %v", len(cmds), cmds)
+	}
+	// the value must reach the remote shell as an inert single-quoted literal,
+	// not as a double-quoted string where $(...) still expands
+	if !strings.Contains(cmds[0], `export FOO='x$(touch /tmp/pwned)'`) {
+		t.Errorf("expected FOO single-quoted, got %q", cmds[0])
+	}
+}
+
+func TestAuthRequiresBearerToken(t *testing.T) {
+	srv := New(testPlayBook(), func(config.Target) (remote.Connector, error) {
+		return &fakeConnector{sess: &fakeSession{}}, nil
+	}, 1)
+	srv.Token = "secret"
+
+	handler := srv.auth(srv.routes())
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 with the right token, got %d", w.Code)
+	}
+}
+
+func TestHandleJobByIDNotFound(t *testing.T) {
+	srv := New(testPlayBook(), func(config.Target) (remote.Connector, error) {
+		return &fakeConnector{sess: &fakeSession{}}, nil
+	}, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/nope", nil)
+	w := httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown job, got %d", w.Code)
+	}
+}
+
+func TestEvictLockedKeepsRunningJobs(t *testing.T) {
+	srv := New(testPlayBook(), func(config.Target) (remote.Connector, error) {
+		return &fakeConnector{sess: &fakeSession{}}, nil
+	}, 1)
+	srv.HistoryCap = 1
+
+	srv.mu.Lock()
+	j1 := newJob("1", "deploy", "default", func() {})
+	j1.status = StatusDone
+	srv.jobs["1"] = j1
+	srv.order = append(srv.order, "1")
+
+	j2 := newJob("2", "deploy", "default", func() {}) // left running
+	srv.jobs["2"] = j2
+	srv.order = append(srv.order, "2")
+
+	j3 := newJob("3", "deploy", "default", func() {})
+	j3.status = StatusDone
+	srv.jobs["3"] = j3
+	srv.order = append(srv.order, "3")
+	srv.evictLocked()
+	srv.mu.Unlock()
+
+	if _, ok := srv.job("1"); ok {
+		t.Error("expected the oldest finished job to be evicted")
+	}
+	if _, ok := srv.job("2"); !ok {
+		t.Error("expected the still-running job to survive eviction")
+	}
+}
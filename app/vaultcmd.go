@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/umputun/simplotask/app/secrets"
+)
+
+// vaultCommand groups the `spt vault` subcommands used to manage secrets
+// committed inline in spt.yml, round-tripping either single values or whole files.
+type vaultCommand struct {
+	Encrypt vaultEncryptCommand `command:"encrypt" description:"encrypt a value or file"`
+	Decrypt vaultDecryptCommand `command:"decrypt" description:"decrypt a value or file"`
+	Edit    vaultEditCommand    `command:"edit" description:"decrypt a file, open it in $EDITOR, re-encrypt on save"`
+}
+
+type vaultKeyOpts struct {
+	VaultKey     string `long:"vault-key" env:"SPOT_VAULT_KEY" description:"vault key to encrypt/decrypt with"`
+	VaultKeyFile string `long:"vault-key-file" description:"file with the vault key, alternative to --vault-key"`
+}
+
+type vaultEncryptCommand struct {
+	vaultKeyOpts
+	Value string `short:"v" long:"value" description:"value to encrypt, alternative to --file"`
+	File  string `short:"f" long:"file" description:"file to encrypt in place, alternative to --value"`
+}
+
+type vaultDecryptCommand struct {
+	vaultKeyOpts
+	Value string `short:"v" long:"value" description:"value to decrypt, alternative to --file"`
+	File  string `short:"f" long:"file" description:"file to decrypt in place, alternative to --value"`
+}
+
+type vaultEditCommand struct {
+	vaultKeyOpts
+	File string `short:"f" long:"file" description:"file to edit" required:"true"`
+}
+
+// resolveVaultKey picks the vault passphrase from an explicit flag value,
+// falling back to a keyfile.
+func resolveVaultKey(opts vaultKeyOpts) (string, error) {
+	if opts.VaultKey != "" {
+		return opts.VaultKey, nil
+	}
+	if opts.VaultKeyFile != "" {
+		return secrets.ReadKeyFile(opts.VaultKeyFile)
+	}
+	return "", fmt.Errorf("no vault key provided, use --vault-key, $SPOT_VAULT_KEY or --vault-key-file")
+}
+
+func (c *vaultEncryptCommand) Execute(_ []string) error {
+	v, err := newVault(c.vaultKeyOpts)
+	if err != nil {
+		return err
+	}
+
+	if c.Value != "" {
+		enc, err := v.Encrypt(c.Value)
+		if err != nil {
+			return fmt.Errorf("can't encrypt value: %w", err)
+		}
+		fmt.Println(enc)
+		return nil
+	}
+	return encryptFile(v, c.File)
+}
+
+func (c *vaultDecryptCommand) Execute(_ []string) error {
+	v, err := newVault(c.vaultKeyOpts)
+	if err != nil {
+		return err
+	}
+
+	if c.Value != "" {
+		dec, err := v.Decrypt(c.Value)
+		if err != nil {
+			return fmt.Errorf("can't decrypt value: %w", err)
+		}
+		fmt.Println(dec)
+		return nil
+	}
+	return decryptFile(v, c.File)
+}
+
+func (c *vaultEditCommand) Execute(_ []string) error {
+	v, err := newVault(c.vaultKeyOpts)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(c.File)
+	if err != nil {
+		return fmt.Errorf("can't read %s: %w", c.File, err)
+	}
+	plain, err := v.Decrypt(string(data))
+	if err != nil {
+		return fmt.Errorf("can't decrypt %s: %w", c.File, err)
+	}
+
+	tmp, err := os.CreateTemp("", "spt-vault-*")
+	if err != nil {
+		return fmt.Errorf("can't create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // best effort cleanup
+
+	if _, err := tmp.WriteString(plain); err != nil {
+		return fmt.Errorf("can't write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("can't close temp file: %w", err)
+	}
+
+	if err := openInEditor(tmp.Name()); err != nil {
+		return fmt.Errorf("can't open editor: %w", err)
+	}
+
+	edited, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("can't read edited file: %w", err)
+	}
+	enc, err := v.Encrypt(string(edited))
+	if err != nil {
+		return fmt.Errorf("can't encrypt edited file: %w", err)
+	}
+	return os.WriteFile(c.File, []byte(enc), 0o600)
+}
+
+func newVault(opts vaultKeyOpts) (*secrets.Vault, error) {
+	key, err := resolveVaultKey(opts)
+	if err != nil {
+		return nil, err
+	}
+	v, err := secrets.New(key)
+	if err != nil {
+		return nil, fmt.Errorf("can't init vault: %w", err)
+	}
+	return v, nil
+}
+
+// openInEditor opens path in $EDITOR (falling back to vi), waiting for it to exit.
+func openInEditor(path string) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command(editor, path) //nolint:gosec // editor/path come from trusted local env and flags
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func encryptFile(v *secrets.Vault, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("can't read %s: %w", path, err)
+	}
+	enc, err := v.Encrypt(string(data))
+	if err != nil {
+		return fmt.Errorf("can't encrypt %s: %w", path, err)
+	}
+	return os.WriteFile(path, []byte(enc), 0o600)
+}
+
+func decryptFile(v *secrets.Vault, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("can't read %s: %w", path, err)
+	}
+	dec, err := v.Decrypt(string(data))
+	if err != nil {
+		return fmt.Errorf("can't decrypt %s: %w", path, err)
+	}
+	return os.WriteFile(path, []byte(dec), 0o600)
+}